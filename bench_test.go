@@ -138,3 +138,14 @@ func BenchmarkReadFrom(b *testing.B) {
 		bufferReadFrom = n
 	})
 }
+
+func BenchmarkClone(b *testing.B) {
+	tpl := flexbuf.With(make([]byte, 1<<20))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		c := tpl.Clone()
+		_, _ = c.WriteAt([]byte{1}, 1000)
+		_ = c.Close()
+	}
+}
@@ -0,0 +1,568 @@
+package flexbuf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Methods reading and writing fixed size numbers use given byte order.
+// Read methods return io.EOF when no bytes are available and
+// io.ErrUnexpectedEOF when the buffer ends in the middle of the value,
+// in both cases the offset is not changed. The ...At forms use absolute
+// offsets and don't change the offset, like ReadAt and WriteAt do.
+// None of the methods allocate.
+
+// next returns next n bytes of the buffer for reading and advances
+// the offset by n.
+func (b *Buffer) next(n int) ([]byte, error) {
+	p, err := b.peek(b.off, n)
+	if err != nil {
+		return nil, err
+	}
+	b.off += n
+	return p, nil
+}
+
+// nextAt returns n bytes of the buffer at absolute offset off for
+// reading.
+func (b *Buffer) nextAt(off int64, n int) ([]byte, error) {
+	i, err := b.rel(off)
+	if err != nil {
+		return nil, err
+	}
+	return b.peek(i, n)
+}
+
+// peek returns n bytes of the buffer at index i.
+func (b *Buffer) peek(i, n int) ([]byte, error) {
+	switch {
+	case n == 0:
+		return nil, nil
+	case i >= len(b.buf):
+		return nil, io.EOF
+	case len(b.buf)-i < n:
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b.buf[i : i+n], nil
+}
+
+// ReadUint16 reads uint16 at the current offset and advances the offset.
+func (b *Buffer) ReadUint16(order binary.ByteOrder) (uint16, error) {
+	p, err := b.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint16(p), nil
+}
+
+// ReadUint16At reads uint16 at offset off.
+func (b *Buffer) ReadUint16At(order binary.ByteOrder, off int64) (uint16, error) {
+	p, err := b.nextAt(off, 2)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint16(p), nil
+}
+
+// ReadUint16s reads len(dst) uint16 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadUint16s(order binary.ByteOrder, dst []uint16) error {
+	p, err := b.next(2 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = order.Uint16(p[2*i:])
+	}
+	return nil
+}
+
+// WriteUint16 writes uint16 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteUint16(order binary.ByteOrder, v uint16) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint16(b.slot(2), v)
+	return nil
+}
+
+// WriteUint16At writes uint16 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteUint16At(order binary.ByteOrder, off int64, v uint16) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 2)
+	if err != nil {
+		return err
+	}
+	order.PutUint16(p, v)
+	return nil
+}
+
+// WriteUint16s writes uint16 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteUint16s(order binary.ByteOrder, src []uint16) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(2 * len(src))
+	for i, v := range src {
+		order.PutUint16(p[2*i:], v)
+	}
+	return nil
+}
+
+// ReadInt16 reads int16 at the current offset and advances the offset.
+func (b *Buffer) ReadInt16(order binary.ByteOrder) (int16, error) {
+	p, err := b.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(order.Uint16(p)), nil
+}
+
+// ReadInt16At reads int16 at offset off.
+func (b *Buffer) ReadInt16At(order binary.ByteOrder, off int64) (int16, error) {
+	p, err := b.nextAt(off, 2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(order.Uint16(p)), nil
+}
+
+// ReadInt16s reads len(dst) int16 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadInt16s(order binary.ByteOrder, dst []int16) error {
+	p, err := b.next(2 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = int16(order.Uint16(p[2*i:]))
+	}
+	return nil
+}
+
+// WriteInt16 writes int16 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteInt16(order binary.ByteOrder, v int16) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint16(b.slot(2), uint16(v))
+	return nil
+}
+
+// WriteInt16At writes int16 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteInt16At(order binary.ByteOrder, off int64, v int16) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 2)
+	if err != nil {
+		return err
+	}
+	order.PutUint16(p, uint16(v))
+	return nil
+}
+
+// WriteInt16s writes int16 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteInt16s(order binary.ByteOrder, src []int16) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(2 * len(src))
+	for i, v := range src {
+		order.PutUint16(p[2*i:], uint16(v))
+	}
+	return nil
+}
+
+// ReadUint32 reads uint32 at the current offset and advances the offset.
+func (b *Buffer) ReadUint32(order binary.ByteOrder) (uint32, error) {
+	p, err := b.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint32(p), nil
+}
+
+// ReadUint32At reads uint32 at offset off.
+func (b *Buffer) ReadUint32At(order binary.ByteOrder, off int64) (uint32, error) {
+	p, err := b.nextAt(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint32(p), nil
+}
+
+// ReadUint32s reads len(dst) uint32 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadUint32s(order binary.ByteOrder, dst []uint32) error {
+	p, err := b.next(4 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = order.Uint32(p[4*i:])
+	}
+	return nil
+}
+
+// WriteUint32 writes uint32 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteUint32(order binary.ByteOrder, v uint32) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint32(b.slot(4), v)
+	return nil
+}
+
+// WriteUint32At writes uint32 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteUint32At(order binary.ByteOrder, off int64, v uint32) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 4)
+	if err != nil {
+		return err
+	}
+	order.PutUint32(p, v)
+	return nil
+}
+
+// WriteUint32s writes uint32 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteUint32s(order binary.ByteOrder, src []uint32) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(4 * len(src))
+	for i, v := range src {
+		order.PutUint32(p[4*i:], v)
+	}
+	return nil
+}
+
+// ReadInt32 reads int32 at the current offset and advances the offset.
+func (b *Buffer) ReadInt32(order binary.ByteOrder) (int32, error) {
+	p, err := b.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(order.Uint32(p)), nil
+}
+
+// ReadInt32At reads int32 at offset off.
+func (b *Buffer) ReadInt32At(order binary.ByteOrder, off int64) (int32, error) {
+	p, err := b.nextAt(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(order.Uint32(p)), nil
+}
+
+// ReadInt32s reads len(dst) int32 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadInt32s(order binary.ByteOrder, dst []int32) error {
+	p, err := b.next(4 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = int32(order.Uint32(p[4*i:]))
+	}
+	return nil
+}
+
+// WriteInt32 writes int32 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteInt32(order binary.ByteOrder, v int32) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint32(b.slot(4), uint32(v))
+	return nil
+}
+
+// WriteInt32At writes int32 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteInt32At(order binary.ByteOrder, off int64, v int32) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 4)
+	if err != nil {
+		return err
+	}
+	order.PutUint32(p, uint32(v))
+	return nil
+}
+
+// WriteInt32s writes int32 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteInt32s(order binary.ByteOrder, src []int32) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(4 * len(src))
+	for i, v := range src {
+		order.PutUint32(p[4*i:], uint32(v))
+	}
+	return nil
+}
+
+// ReadUint64 reads uint64 at the current offset and advances the offset.
+func (b *Buffer) ReadUint64(order binary.ByteOrder) (uint64, error) {
+	p, err := b.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint64(p), nil
+}
+
+// ReadUint64At reads uint64 at offset off.
+func (b *Buffer) ReadUint64At(order binary.ByteOrder, off int64) (uint64, error) {
+	p, err := b.nextAt(off, 8)
+	if err != nil {
+		return 0, err
+	}
+	return order.Uint64(p), nil
+}
+
+// ReadUint64s reads len(dst) uint64 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadUint64s(order binary.ByteOrder, dst []uint64) error {
+	p, err := b.next(8 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = order.Uint64(p[8*i:])
+	}
+	return nil
+}
+
+// WriteUint64 writes uint64 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteUint64(order binary.ByteOrder, v uint64) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint64(b.slot(8), v)
+	return nil
+}
+
+// WriteUint64At writes uint64 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteUint64At(order binary.ByteOrder, off int64, v uint64) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 8)
+	if err != nil {
+		return err
+	}
+	order.PutUint64(p, v)
+	return nil
+}
+
+// WriteUint64s writes uint64 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteUint64s(order binary.ByteOrder, src []uint64) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(8 * len(src))
+	for i, v := range src {
+		order.PutUint64(p[8*i:], v)
+	}
+	return nil
+}
+
+// ReadInt64 reads int64 at the current offset and advances the offset.
+func (b *Buffer) ReadInt64(order binary.ByteOrder) (int64, error) {
+	p, err := b.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(order.Uint64(p)), nil
+}
+
+// ReadInt64At reads int64 at offset off.
+func (b *Buffer) ReadInt64At(order binary.ByteOrder, off int64) (int64, error) {
+	p, err := b.nextAt(off, 8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(order.Uint64(p)), nil
+}
+
+// ReadInt64s reads len(dst) int64 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadInt64s(order binary.ByteOrder, dst []int64) error {
+	p, err := b.next(8 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = int64(order.Uint64(p[8*i:]))
+	}
+	return nil
+}
+
+// WriteInt64 writes int64 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteInt64(order binary.ByteOrder, v int64) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint64(b.slot(8), uint64(v))
+	return nil
+}
+
+// WriteInt64At writes int64 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteInt64At(order binary.ByteOrder, off int64, v int64) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 8)
+	if err != nil {
+		return err
+	}
+	order.PutUint64(p, uint64(v))
+	return nil
+}
+
+// WriteInt64s writes int64 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteInt64s(order binary.ByteOrder, src []int64) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(8 * len(src))
+	for i, v := range src {
+		order.PutUint64(p[8*i:], uint64(v))
+	}
+	return nil
+}
+
+// ReadFloat32 reads float32 at the current offset and advances the offset.
+func (b *Buffer) ReadFloat32(order binary.ByteOrder) (float32, error) {
+	p, err := b.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(order.Uint32(p)), nil
+}
+
+// ReadFloat32At reads float32 at offset off.
+func (b *Buffer) ReadFloat32At(order binary.ByteOrder, off int64) (float32, error) {
+	p, err := b.nextAt(off, 4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(order.Uint32(p)), nil
+}
+
+// ReadFloat32s reads len(dst) float32 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadFloat32s(order binary.ByteOrder, dst []float32) error {
+	p, err := b.next(4 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = math.Float32frombits(order.Uint32(p[4*i:]))
+	}
+	return nil
+}
+
+// WriteFloat32 writes float32 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteFloat32(order binary.ByteOrder, v float32) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint32(b.slot(4), math.Float32bits(v))
+	return nil
+}
+
+// WriteFloat32At writes float32 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteFloat32At(order binary.ByteOrder, off int64, v float32) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 4)
+	if err != nil {
+		return err
+	}
+	order.PutUint32(p, math.Float32bits(v))
+	return nil
+}
+
+// WriteFloat32s writes float32 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteFloat32s(order binary.ByteOrder, src []float32) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(4 * len(src))
+	for i, v := range src {
+		order.PutUint32(p[4*i:], math.Float32bits(v))
+	}
+	return nil
+}
+
+// ReadFloat64 reads float64 at the current offset and advances the offset.
+func (b *Buffer) ReadFloat64(order binary.ByteOrder) (float64, error) {
+	p, err := b.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(order.Uint64(p)), nil
+}
+
+// ReadFloat64At reads float64 at offset off.
+func (b *Buffer) ReadFloat64At(order binary.ByteOrder, off int64) (float64, error) {
+	p, err := b.nextAt(off, 8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(order.Uint64(p)), nil
+}
+
+// ReadFloat64s reads len(dst) float64 values at the current offset and
+// advances the offset.
+func (b *Buffer) ReadFloat64s(order binary.ByteOrder, dst []float64) error {
+	p, err := b.next(8 * len(dst))
+	if err != nil {
+		return err
+	}
+	for i := range dst {
+		dst[i] = math.Float64frombits(order.Uint64(p[8*i:]))
+	}
+	return nil
+}
+
+// WriteFloat64 writes float64 at the current offset and advances the offset.
+// It always returns nil error.
+func (b *Buffer) WriteFloat64(order binary.ByteOrder, v float64) error {
+	b.lock()
+	defer b.unlock()
+	order.PutUint64(b.slot(8), math.Float64bits(v))
+	return nil
+}
+
+// WriteFloat64At writes float64 at offset off. It returns ErrOutOfBounds when
+// off is negative or points to discarded data.
+func (b *Buffer) WriteFloat64At(order binary.ByteOrder, off int64, v float64) error {
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, 8)
+	if err != nil {
+		return err
+	}
+	order.PutUint64(p, math.Float64bits(v))
+	return nil
+}
+
+// WriteFloat64s writes float64 values from src at the current offset and
+// advances the offset. It always returns nil error.
+func (b *Buffer) WriteFloat64s(order binary.ByteOrder, src []float64) error {
+	b.lock()
+	defer b.unlock()
+	p := b.slot(8 * len(src))
+	for i, v := range src {
+		order.PutUint64(p[8*i:], math.Float64bits(v))
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package flexbuf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Buffer_WriteUint16(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		order binary.ByteOrder
+		exp   []byte
+	}{
+		{"big endian", binary.BigEndian, []byte{0, 0x01, 0x02}},
+		{"little endian", binary.LittleEndian, []byte{0, 0x02, 0x01}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With([]byte{0}, Append)
+
+			// --- When ---
+			err := buf.WriteUint16(tc.order, 0x0102)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.exp, buf.buf)
+			assert.Exactly(t, 3, buf.Offset())
+		})
+	}
+}
+
+func Test_Buffer_Binary_RoundTrip(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	order := binary.LittleEndian
+
+	// --- When ---
+	require.NoError(t, buf.WriteUint16(order, 0xfffe))
+	require.NoError(t, buf.WriteInt16(order, -2))
+	require.NoError(t, buf.WriteUint32(order, 0xfffffffe))
+	require.NoError(t, buf.WriteInt32(order, -3))
+	require.NoError(t, buf.WriteUint64(order, math.MaxUint64))
+	require.NoError(t, buf.WriteInt64(order, math.MinInt64))
+	require.NoError(t, buf.WriteFloat32(order, 1.5))
+	require.NoError(t, buf.WriteFloat64(order, -2.25))
+
+	// --- Then ---
+	assert.Exactly(t, 40, buf.Len())
+	buf.SeekStart()
+
+	u16, err := buf.ReadUint16(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint16(0xfffe), u16)
+	i16, err := buf.ReadInt16(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, int16(-2), i16)
+	u32, err := buf.ReadUint32(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint32(0xfffffffe), u32)
+	i32, err := buf.ReadInt32(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, int32(-3), i32)
+	u64, err := buf.ReadUint64(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(math.MaxUint64), u64)
+	i64, err := buf.ReadInt64(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(math.MinInt64), i64)
+	f32, err := buf.ReadFloat32(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, float32(1.5), f32)
+	f64, err := buf.ReadFloat64(order)
+	assert.NoError(t, err)
+	assert.Exactly(t, -2.25, f64)
+
+	_, err = buf.ReadUint16(order)
+	assert.Exactly(t, io.EOF, err)
+}
+
+func Test_Buffer_Binary_At(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2})
+	order := binary.BigEndian
+
+	// --- When ---
+	require.NoError(t, buf.WriteUint32At(order, 2, 0x01020304))
+	require.NoError(t, buf.WriteInt16At(order, 8, -1))
+	require.NoError(t, buf.WriteFloat64At(order, 10, 0.5))
+
+	// --- Then ---
+	assert.Exactly(t, 0, buf.Offset())
+	assert.Exactly(t, 18, buf.Len())
+	assert.Exactly(t, []byte{0, 1, 1, 2, 3, 4, 0, 0, 0xff, 0xff}, buf.buf[:10])
+
+	u32, err := buf.ReadUint32At(order, 2)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint32(0x01020304), u32)
+	i16, err := buf.ReadInt16At(order, 8)
+	assert.NoError(t, err)
+	assert.Exactly(t, int16(-1), i16)
+	f64, err := buf.ReadFloat64At(order, 10)
+	assert.NoError(t, err)
+	assert.Exactly(t, 0.5, f64)
+	u64, err := buf.ReadUint64At(order, 10)
+	assert.NoError(t, err)
+	assert.Exactly(t, math.Float64bits(0.5), u64)
+	assert.Exactly(t, 0, buf.Offset())
+}
+
+func Test_Buffer_Binary_Errors(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2}, Offset(1))
+	order := binary.BigEndian
+
+	// --- Then ---
+	_, err := buf.ReadUint32(order)
+	assert.Exactly(t, io.ErrUnexpectedEOF, err)
+	assert.Exactly(t, 1, buf.Offset())
+
+	_, err = buf.ReadUint16At(order, 3)
+	assert.Exactly(t, io.EOF, err)
+	_, err = buf.ReadUint16At(order, 2)
+	assert.Exactly(t, io.ErrUnexpectedEOF, err)
+	_, err = buf.ReadUint16At(order, -1)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	assert.Exactly(t, ErrOutOfBounds, buf.WriteUint16At(order, -1, 0))
+
+	require.NoError(t, buf.Discard(2))
+	_, err = buf.ReadInt64At(order, 1)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	assert.Exactly(t, ErrOutOfBounds, buf.WriteFloat32At(order, 1, 0))
+}
+
+func Test_Buffer_Binary_Slices(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	order := binary.LittleEndian
+
+	// --- When ---
+	require.NoError(t, buf.WriteUint16s(order, []uint16{1, 2}))
+	require.NoError(t, buf.WriteInt32s(order, []int32{-1, 3}))
+	require.NoError(t, buf.WriteFloat64s(order, []float64{0.5, -0.5}))
+	require.NoError(t, buf.WriteUint64s(order, nil))
+
+	// --- Then ---
+	assert.Exactly(t, 28, buf.Len())
+	assert.Exactly(t, []byte{1, 0, 2, 0, 0xff, 0xff, 0xff, 0xff, 3, 0, 0, 0}, buf.buf[:12])
+	buf.SeekStart()
+
+	u16 := make([]uint16, 2)
+	assert.NoError(t, buf.ReadUint16s(order, u16))
+	assert.Exactly(t, []uint16{1, 2}, u16)
+	i32 := make([]int32, 2)
+	assert.NoError(t, buf.ReadInt32s(order, i32))
+	assert.Exactly(t, []int32{-1, 3}, i32)
+	f64 := make([]float64, 3)
+	assert.Exactly(t, io.ErrUnexpectedEOF, buf.ReadFloat64s(order, f64))
+	assert.NoError(t, buf.ReadFloat64s(order, f64[:2]))
+	assert.Exactly(t, []float64{0.5, -0.5, 0}, f64)
+	assert.NoError(t, buf.ReadUint64s(order, nil))
+}
+
+func Test_Buffer_Binary_NoAllocs(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	buf.Grow(1 << 10)
+	order := binary.BigEndian
+	vs := make([]uint32, 4)
+
+	// --- When ---
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.SeekStart()
+		_ = buf.WriteUint64(order, 1)
+		_ = buf.WriteFloat32At(order, 8, 1)
+		_ = buf.WriteUint32s(order, vs)
+		buf.SeekStart()
+		_, _ = buf.ReadUint64(order)
+		_, _ = buf.ReadFloat32At(order, 8)
+		_ = buf.ReadUint32s(order, vs)
+	})
+
+	// --- Then ---
+	assert.Exactly(t, float64(0), allocs)
+}
@@ -0,0 +1,223 @@
+package flexbuf
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrBitCount is returned when more than 64 bits are read or written
+// at once.
+var ErrBitCount = errors.New("invalid bit count")
+
+// BitOrder specifies the order of bits in a byte.
+type BitOrder int
+
+// Bit orders.
+const (
+	// MSBFirst reads and writes the most significant bit of a byte first
+	// and values starting from their most significant bit. It's used by
+	// most network protocols, JPEG and H.264.
+	MSBFirst BitOrder = iota
+	// LSBFirst reads and writes the least significant bit of a byte first
+	// and values starting from their least significant bit. It's used by
+	// DEFLATE and GIF.
+	LSBFirst
+)
+
+// bitPos is the bit position in the Buffer shared by BitReader and
+// BitWriter.
+type bitPos struct {
+	// Buffer to read or write.
+	buf *Buffer
+	// Bit order.
+	order BitOrder
+	// Absolute bit offset.
+	pos int64
+}
+
+// BitOffset returns the absolute offset of the next bit.
+func (bp *bitPos) BitOffset() int64 {
+	return bp.pos
+}
+
+// SeekBit sets the offset of the next bit to offset, interpreted according
+// to whence: io.SeekStart, io.SeekCurrent or io.SeekEnd (relative to
+// the end of the buffer). It returns the new offset and os.ErrInvalid if
+// calculated offset is negative.
+func (bp *bitPos) SeekBit(offset int64, whence int) (int64, error) {
+	var off int64
+	switch whence {
+	case io.SeekStart:
+		off = offset
+	case io.SeekCurrent:
+		off = bp.pos + offset
+	case io.SeekEnd:
+		off = bp.buf.Size()*8 + offset
+	}
+
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	bp.pos = off
+	return off, nil
+}
+
+// Align skips bits up to the next byte boundary. It returns the number
+// of skipped bits.
+func (bp *bitPos) Align() int {
+	n := int(-bp.pos & 7)
+	bp.pos += int64(n)
+	return n
+}
+
+// span returns the number of bits which can be processed in the byte at
+// bit offset off when n bits are left and the shift of the least
+// significant bit of the processed bits in that byte.
+func (bp *bitPos) span(off int64, n uint) (uint, uint) {
+	k := uint(off & 7)
+	m := 8 - k
+	if m > n {
+		m = n
+	}
+	if bp.order == MSBFirst {
+		return m, 8 - k - m
+	}
+	return m, k
+}
+
+// BitReader reads bits from the Buffer. It reads from its own bit offset
+// starting at the buffer offset and doesn't change the buffer offset.
+type BitReader struct {
+	bitPos
+}
+
+// NewBitReader returns new BitReader reading buf in given bit order
+// starting at the buffer offset.
+func NewBitReader(buf *Buffer, order BitOrder) *BitReader {
+	return &BitReader{
+		bitPos: bitPos{
+			buf:   buf,
+			order: order,
+			pos:   int64(buf.Offset()) * 8,
+		},
+	}
+}
+
+// ReadBits reads n bits and returns them as the least significant bits
+// of the returned value. It returns io.EOF when no bits are available,
+// io.ErrUnexpectedEOF when fewer than n bits are available (the bit
+// offset is not changed then) and ErrBitCount when n is greater than 64.
+func (r *BitReader) ReadBits(n uint) (uint64, error) {
+	v, err := r.ReadBitsAt(r.pos, n)
+	if err != nil {
+		return 0, err
+	}
+	r.pos += int64(n)
+	return v, nil
+}
+
+// ReadBit reads single bit.
+func (r *BitReader) ReadBit() (bool, error) {
+	v, err := r.ReadBits(1)
+	return v == 1, err
+}
+
+// ReadBitsAt reads n bits starting at absolute bit offset off. It doesn't
+// change the bit offset. See ReadBits for returned errors, additionally it
+// returns ErrOutOfBounds when off is negative or points to discarded data.
+func (r *BitReader) ReadBitsAt(off int64, n uint) (uint64, error) {
+	if n > 64 {
+		return 0, ErrBitCount
+	}
+	if _, err := r.buf.rel(off >> 3); err != nil || n == 0 {
+		return 0, err
+	}
+
+	size := r.buf.Size() * 8
+	switch {
+	case off >= size:
+		return 0, io.EOF
+	case off+int64(n) > size:
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	var v uint64
+	for got := uint(0); got < n; {
+		i, _ := r.buf.rel(off >> 3)
+		m, shift := r.span(off, n-got)
+		chunk := uint64(r.buf.buf[i]>>shift) & (1<<m - 1)
+		if r.order == MSBFirst {
+			v = v<<m | chunk
+		} else {
+			v |= chunk << got
+		}
+		got += m
+		off += int64(m)
+	}
+	return v, nil
+}
+
+// BitWriter writes bits to the Buffer growing it as needed. It writes at
+// its own bit offset starting at the buffer offset and doesn't change the
+// buffer offset. Bits of the partially written bytes which are not written
+// keep their values.
+type BitWriter struct {
+	bitPos
+}
+
+// NewBitWriter returns new BitWriter writing to buf in given bit order
+// starting at the buffer offset.
+func NewBitWriter(buf *Buffer, order BitOrder) *BitWriter {
+	return &BitWriter{
+		bitPos: bitPos{
+			buf:   buf,
+			order: order,
+			pos:   int64(buf.Offset()) * 8,
+		},
+	}
+}
+
+// WriteBits writes n least significant bits of v. It returns ErrBitCount
+// when n is greater than 64 and ErrOutOfBounds when the bit offset points
+// to discarded data.
+func (w *BitWriter) WriteBits(v uint64, n uint) error {
+	if n > 64 {
+		return ErrBitCount
+	}
+
+	b := w.buf
+	b.lock()
+	defer b.unlock()
+
+	for n > 0 {
+		p, err := b.slotAt(w.pos>>3, 1)
+		if err != nil {
+			return err
+		}
+
+		m, shift := w.span(w.pos, n)
+		mask := uint64(1)<<m - 1
+		var chunk uint64
+		if w.order == MSBFirst {
+			chunk = v >> (n - m) & mask
+		} else {
+			chunk = v & mask
+			v >>= m
+		}
+		p[0] = p[0]&^byte(mask<<shift) | byte(chunk<<shift)
+
+		n -= m
+		w.pos += int64(m)
+	}
+	return nil
+}
+
+// WriteBit writes single bit.
+func (w *BitWriter) WriteBit(bit bool) error {
+	var v uint64
+	if bit {
+		v = 1
+	}
+	return w.WriteBits(v, 1)
+}
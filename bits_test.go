@@ -0,0 +1,255 @@
+package flexbuf
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BitWriter_WriteBits(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		order BitOrder
+		exp   []byte
+	}{
+		{"msb", MSBFirst, []byte{0b101_11110, 0b0001_0000}},
+		{"lsb", LSBFirst, []byte{0b00001_101, 0b0000_1111}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := &Buffer{}
+			w := NewBitWriter(buf, tc.order)
+
+			// --- When ---
+			require.NoError(t, w.WriteBits(0b101, 3))
+			require.NoError(t, w.WriteBits(0b11110_0001, 9))
+
+			// --- Then ---
+			assert.Exactly(t, tc.exp, buf.buf)
+			assert.Exactly(t, int64(12), w.BitOffset())
+			assert.Exactly(t, 0, buf.Offset())
+		})
+	}
+}
+
+func Test_BitReader_ReadBits(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		order BitOrder
+		data  []byte
+	}{
+		{"msb", MSBFirst, []byte{0b101_11110, 0b0001_0000}},
+		{"lsb", LSBFirst, []byte{0b00001_101, 0b0000_1111}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			r := NewBitReader(With(tc.data), tc.order)
+
+			// --- When ---
+			v1, err1 := r.ReadBits(3)
+			v2, err2 := r.ReadBits(9)
+
+			// --- Then ---
+			assert.NoError(t, err1)
+			assert.NoError(t, err2)
+			assert.Exactly(t, uint64(0b101), v1)
+			assert.Exactly(t, uint64(0b11110_0001), v2)
+			assert.Exactly(t, int64(12), r.BitOffset())
+
+			_, err := r.ReadBits(5)
+			assert.Exactly(t, io.ErrUnexpectedEOF, err)
+			assert.Exactly(t, int64(12), r.BitOffset())
+
+			v, err := r.ReadBits(4)
+			assert.NoError(t, err)
+			assert.Exactly(t, uint64(0), v)
+
+			_, err = r.ReadBits(1)
+			assert.Exactly(t, io.EOF, err)
+		})
+	}
+}
+
+func Test_Bits_RoundTrip(t *testing.T) {
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		// --- Given ---
+		rnd := rand.New(rand.NewSource(1))
+		buf := &Buffer{}
+		w := NewBitWriter(buf, order)
+
+		var vs []uint64
+		var ns []uint
+		for i := 0; i < 1000; i++ {
+			n := uint(rnd.Intn(65))
+			v := rnd.Uint64()
+			if n < 64 {
+				v &= 1<<n - 1
+			}
+			vs = append(vs, v)
+			ns = append(ns, n)
+
+			// --- When ---
+			require.NoError(t, w.WriteBits(v, n))
+		}
+
+		// --- Then ---
+		r := NewBitReader(buf, order)
+		for i := range vs {
+			v, err := r.ReadBits(ns[i])
+			require.NoError(t, err)
+			require.Exactly(t, vs[i], v, "order %d value %d", order, i)
+		}
+		assert.Exactly(t, w.BitOffset(), r.BitOffset())
+	}
+}
+
+func Test_BitWriter_KeepsBits(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0xff, 0xff})
+	w := NewBitWriter(buf, MSBFirst)
+	_, err := w.SeekBit(6, io.SeekStart)
+	require.NoError(t, err)
+
+	// --- When ---
+	err = w.WriteBits(0, 4)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0b11111100, 0b00111111}, buf.buf)
+}
+
+func Test_BitWriter_WriteBit(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	w := NewBitWriter(buf, LSBFirst)
+
+	// --- When ---
+	require.NoError(t, w.WriteBit(true))
+	require.NoError(t, w.WriteBit(false))
+	require.NoError(t, w.WriteBit(true))
+
+	// --- Then ---
+	assert.Exactly(t, []byte{0b101}, buf.buf)
+	r := NewBitReader(buf, LSBFirst)
+	bit, err := r.ReadBit()
+	assert.NoError(t, err)
+	assert.True(t, bit)
+	bit, err = r.ReadBit()
+	assert.NoError(t, err)
+	assert.False(t, bit)
+}
+
+func Test_Bits_Align(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	w := NewBitWriter(buf, MSBFirst)
+	require.NoError(t, w.WriteBits(1, 1))
+
+	// --- When ---
+	n := w.Align()
+
+	// --- Then ---
+	assert.Exactly(t, 7, n)
+	assert.Exactly(t, 0, w.Align())
+	require.NoError(t, w.WriteBits(0xab, 8))
+	assert.Exactly(t, []byte{0x80, 0xab}, buf.buf)
+
+	r := NewBitReader(buf, MSBFirst)
+	_, _ = r.ReadBits(3)
+	assert.Exactly(t, 5, r.Align())
+	v, err := r.ReadBits(8)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(0xab), v)
+}
+
+func Test_Bits_SeekBit(t *testing.T) {
+	// --- Given ---
+	r := NewBitReader(With([]byte{0x0f, 0xf0}), MSBFirst)
+
+	tt := []struct {
+		testN string
+
+		off    int64
+		whence int
+		exp    int64
+		err    error
+	}{
+		{"start", 4, io.SeekStart, 4, nil},
+		{"current", 3, io.SeekCurrent, 7, nil},
+		{"end", -4, io.SeekEnd, 12, nil},
+		{"negative", -13, io.SeekCurrent, 0, os.ErrInvalid},
+	}
+
+	for _, tc := range tt {
+		// --- When ---
+		off, err := r.SeekBit(tc.off, tc.whence)
+
+		// --- Then ---
+		assert.Exactly(t, tc.err, err, tc.testN)
+		assert.Exactly(t, tc.exp, off, tc.testN)
+	}
+	assert.Exactly(t, int64(12), r.BitOffset())
+}
+
+func Test_BitReader_ReadBitsAt(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0x0f, 0xf0, 0xaa})
+	r := NewBitReader(buf, MSBFirst)
+
+	// --- When ---
+	v, err := r.ReadBitsAt(4, 8)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(0xff), v)
+	assert.Exactly(t, int64(0), r.BitOffset())
+
+	_, err = r.ReadBitsAt(-1, 1)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	_, err = r.ReadBitsAt(0, 65)
+	assert.Exactly(t, ErrBitCount, err)
+	_, err = r.ReadBitsAt(24, 1)
+	assert.Exactly(t, io.EOF, err)
+	v, err = r.ReadBitsAt(24, 0)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(0), v)
+
+	require.NoError(t, buf.Discard(1))
+	_, err = r.ReadBitsAt(7, 2)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	v, err = r.ReadBitsAt(8, 8)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(0xf0), v)
+}
+
+func Test_Bits_StartAtBufferOffset(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0x00, 0x00, 0x00}, Offset(1))
+
+	// --- When ---
+	w := NewBitWriter(buf, MSBFirst)
+	require.NoError(t, w.WriteBits(math.MaxUint64, 64))
+
+	// --- Then ---
+	assert.Exactly(t, int64(72), w.BitOffset())
+	assert.Exactly(t, 9, buf.Len())
+	assert.Exactly(t, byte(0), buf.buf[0])
+	assert.Exactly(t, ErrBitCount, w.WriteBits(0, 65))
+
+	r := NewBitReader(buf, MSBFirst)
+	assert.Exactly(t, int64(8), r.BitOffset())
+	v, err := r.ReadBits(64)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(math.MaxUint64), v)
+}
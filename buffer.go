@@ -8,6 +8,9 @@
 //     io.StringWriter
 //     io.Reader
 //     io.ByteReader
+//     io.ByteScanner
+//     io.RuneReader
+//     io.RuneScanner
 //     io.ReaderAt
 //     io.ReaderFrom
 //     io.Seeker
@@ -55,10 +58,40 @@ type Buffer struct {
 	// Flags passed when creating the Buffer.
 	// Flags are used to match behaviour of the Buffer to os.File.
 	flag int
-	// Current offset for read and write operations.
+	// Current offset for read and write operations relative to the
+	// beginning of buf.
 	off int
 	// Underlying buffer.
 	buf []byte
+	// Absolute offset of the first byte of buf.
+	base int64
+	// Number of discarded bytes at the beginning of buf.
+	low int
+	// Allocator used for buffers stored outside of the Go heap.
+	mem allocator
+	// True when buf was allocated by mem.
+	mapped bool
+	// True when buf is not owned by the Buffer and must be copied
+	// before it's modified.
+	cow bool
+	// Reference counter of the storage shared with clones, nil when
+	// the storage is not shared or is owned by the caller.
+	shr *share
+	// True when buf is the private mapping of the clone snapshot which
+	// was not modified since it was made.
+	clean bool
+	// Synchronizes the buffer with its followers, nil when Follow
+	// was never called.
+	tail *tail
+	// Number of placeholders returned by Reserve which were not set.
+	unset int
+	// Placeholders which can be set, nil when Reserve was never called.
+	phs map[*Placeholder]struct{}
+	// Absolute offset after the rune read by the last ReadRune call.
+	runeEnd int64
+	// Size of the rune read by the last ReadRune call, zero when
+	// UnreadRune can't be called.
+	runeLen int
 }
 
 // New returns new instance of the Buffer. The difference between New and
@@ -74,6 +107,7 @@ func New(opts ...func(buffer *Buffer)) *Buffer {
 // NewBuffer is intended to prepare a Buffer to read existing data. It can
 // also be used to set the initial size of the internal buffer for writing.
 // To do that, buf should have the desired capacity but a length of zero.
+// Use CopyOnWrite option when buf must not be modified.
 // It will panic with ErrOutOfBounds if option sets offset as negative number
 // or beyond buffer length.
 func With(data []byte, opts ...func(*Buffer)) *Buffer {
@@ -93,11 +127,25 @@ func With(data []byte, opts ...func(*Buffer)) *Buffer {
 }
 
 // Release releases ownership of the underlying buffer, the caller should not
-// use the instance of Buffer after this call.
+// use the instance of Buffer after this call. If the buffer was stored
+// outside of the Go heap or shares its storage with clones its contents
+// are copied to the Go heap first.
 func (b *Buffer) Release() []byte {
-	buf := b.buf
+	b.lock()
+	defer b.unlock()
+	b.end()
+	buf := b.buf[b.low:]
+	if b.mapped || b.shr != nil {
+		buf = make([]byte, len(buf))
+		copy(buf, b.buf[b.low:])
+	}
+	b.drop()
 	b.off = 0
 	b.buf = nil
+	b.base = 0
+	b.low = 0
+	b.invalidate(0, -1) // All placeholders.
+	b.runeLen = 0
 	return buf
 }
 
@@ -105,34 +153,57 @@ func (b *Buffer) Release() []byte {
 // the buffer as needed. The return value n is the length of p; err is
 // always nil.
 func (b *Buffer) Write(p []byte) (int, error) {
+	b.lock()
+	defer b.unlock()
 	return b.write(p), nil
 }
 
 // WriteByte writes single byte c to the buffer.
 func (b *Buffer) WriteByte(c byte) error {
+	b.lock()
+	defer b.unlock()
 	b.write([]byte{c})
 	return nil
 }
 
 // WriteAt writes len(p) bytes to the buffer starting at byte offset off.
-// It returns the number of bytes written; err is always nil. It does not
-// change the offset.
+// It returns the number of bytes written and ErrOutOfBounds error when
+// off is negative or points to discarded data. It does not change
+// the offset.
 func (b *Buffer) WriteAt(p []byte, off int64) (int, error) {
+	b.lock()
+	defer b.unlock()
+	dst, err := b.slotAt(off, len(p))
+	if err != nil {
+		return 0, err
+	}
+	return copy(dst, p), nil
+}
+
+// slotAt returns n bytes long slice of the buffer starting at absolute
+// offset off for writing, growing the buffer as needed. It does not
+// change the offset.
+func (b *Buffer) slotAt(off int64, n int) ([]byte, error) {
+	i, err := b.rel(off)
+	if err != nil {
+		return nil, err
+	}
+
+	b.own()
 	prev := b.off
 	c := cap(b.buf)
-	pl := len(p)
 
 	// Handle write beyond capacity.
-	if int(off)+pl > c {
+	if i+n > c {
 		b.off = c // So tryGrowByReslice returns false.
-		b.grow(int(off) + pl - len(b.buf))
-		b.buf = b.buf[:int(off)+pl]
+		b.grow(i + n - len(b.buf))
+		b.buf = b.buf[:i+n]
 	}
 
-	b.off = int(off)
-	n := b.write(p)
+	b.off = i
+	p := b.slot(n)
 	b.off = prev
-	return n, nil
+	return p, nil
 }
 
 // WriteTo writes data to w starting at current offset until there's no
@@ -152,15 +223,22 @@ func (b *Buffer) WriteString(s string) (int, error) {
 
 // write writes p at offset b.off.
 func (b *Buffer) write(p []byte) int {
+	return copy(b.slot(len(p)), p)
+}
+
+// slot returns n bytes long slice of the buffer at offset b.off for
+// writing, growing the buffer as needed. It advances the offset by n.
+func (b *Buffer) slot(n int) []byte {
+	b.own()
 	l := len(b.buf)
-	b.grow(len(p))
-	n := copy(b.buf[b.off:], p)
+	b.grow(n)
+	p := b.buf[b.off : b.off+n]
 	b.off += n
 	if b.off > l {
 		l = b.off
 	}
 	b.buf = b.buf[:l]
-	return n
+	return p
 }
 
 // Read reads the next len(p) bytes from the buffer or until the buffer
@@ -192,19 +270,19 @@ func (b *Buffer) ReadByte() (byte, error) {
 
 // ReadAt reads len(p) bytes from the buffer starting at byte offset off.
 // It returns the number of bytes read and the error, if any.
-// ReadAt always returns a non-nil error when n < len(p). It does not
-// change the offset.
+// ReadAt always returns a non-nil error when n < len(p). It returns
+// ErrOutOfBounds when off is negative or points to discarded data. It
+// does not change the offset so it may be called concurrently with
+// other ReadAt calls.
 func (b *Buffer) ReadAt(p []byte, off int64) (int, error) {
-	if off >= int64(len(b.buf)) {
-		return 0, io.EOF
-	}
-	prev := b.off
-	defer func() { b.off = prev }()
-	b.off = int(off)
-	n, err := b.Read(p)
+	i, err := b.rel(off)
 	if err != nil {
-		return n, err
+		return 0, err
+	}
+	if i >= len(b.buf) {
+		return 0, io.EOF
 	}
+	n := copy(p, b.buf[i:])
 	if n < len(p) {
 		return n, io.EOF
 	}
@@ -220,6 +298,8 @@ func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
 	var n, total int
 
 	for {
+		b.lock()
+		b.own()
 
 		// Length before growing the buffer.
 		l := len(b.buf)
@@ -235,11 +315,17 @@ func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
 		// to Read because it might change parts of it not involved in
 		// read operation.
 		tmp := b.buf[l:cap(b.buf)]
+
+		// Don't block followers while reading, they never read
+		// past the buffer length.
+		b.buf = b.buf[:l]
+		b.unlock()
 		n, err = r.Read(tmp)
+		b.lock()
 
 		if l != b.off {
 			// Move bytes from temporary area to correct place.
-			copy(b.buf[b.off:], tmp[:n])
+			copy(b.buf[b.off:cap(b.buf)], tmp[:n])
 			if n < len(tmp) {
 				// Clean up any garbage reader might put in there and
 				// we want to keep all bytes between len and cap as zeros.
@@ -256,6 +342,7 @@ func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
 
 		// Set proper buffer length.
 		b.buf = b.buf[:l]
+		b.unlock()
 
 		if err != nil {
 			break
@@ -282,38 +369,44 @@ func (b *Buffer) String() string {
 // Seek sets the offset for the next Read or Write on the buffer to offset,
 // interpreted according to whence: 0 means relative to the origin of the file,
 // 1 means relative to the current offset, and 2 means relative to the end.
-// It returns the new offset and an error (only if calculated offset < 0).
+// It returns the new offset and an error: os.ErrInvalid if calculated
+// offset < 0 and ErrOutOfBounds if it points to discarded data.
 func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
-	var off int
+	var off int64
 	switch whence {
 	case io.SeekStart:
-		off = int(offset)
+		off = offset
 	case io.SeekCurrent:
-		off = b.off + int(offset)
+		off = b.base + int64(b.off) + offset
 	case io.SeekEnd:
-		off = len(b.buf) + int(offset)
+		off = b.base + int64(len(b.buf)) + offset
 	}
 
 	if off < 0 {
 		return 0, os.ErrInvalid
 	}
-	b.off = off
+	i, err := b.rel(off)
+	if err != nil {
+		return 0, err
+	}
+	b.off = i
 
-	return int64(b.off), nil
+	return off, nil
 }
 
 // SeekStart is a convenience method setting the buffer's offset to zero
-// and returning the value it had before the method was called.
+// (or the first not discarded byte) and returning the value it had before
+// the method was called.
 func (b *Buffer) SeekStart() int64 {
-	prev := b.off
-	b.off = 0
+	prev := b.Offset()
+	b.off = b.low
 	return int64(prev)
 }
 
 // SeekEnd is a convenience method setting the buffer's offset to the buffer
 // length and returning the value it had before the method was called.
 func (b *Buffer) SeekEnd() int64 {
-	prev := b.off
+	prev := b.Offset()
 	b.off = len(b.buf)
 	return int64(prev)
 }
@@ -321,43 +414,61 @@ func (b *Buffer) SeekEnd() int64 {
 // Truncate changes the size of the buffer discarding bytes at offsets greater
 // then size. It does not change the offset unless Append option was used then
 // it sets offset to the end of the buffer. It returns error os.ErrInvalid
-// only when when size is negative.
+// when size is negative and ErrOutOfBounds when it points to discarded
+// data.
 func (b *Buffer) Truncate(size int64) error {
 	if size < 0 {
 		return os.ErrInvalid
 	}
 
+	b.lock()
+	defer b.unlock()
+	return b.truncate(size)
+}
+
+// truncate changes the size of the buffer. See Truncate.
+func (b *Buffer) truncate(size int64) error {
+	n, err := b.rel(size)
+	if err != nil {
+		return err
+	}
+
 	prev := b.off
 	l := len(b.buf)
+	if n != l {
+		b.own() // Shared storage is copied only when it changes.
+	}
 	c := cap(b.buf)
 
 	switch {
-	case int(size) == l:
+	case n == l:
 		// Nothing to do.
 
-	case int(size) == c:
+	case n == c:
 		// Reslice.
-		b.buf = b.buf[:size]
+		b.buf = b.buf[:n]
 
-	case int(size) > l && int(size) < c:
+	case n > l && n < c:
 		// Truncate between len and cap.
-		b.buf = b.buf[:size]
+		b.buf = b.buf[:n]
 
-	case int(size) > c:
+	case n > c:
 		// Truncate beyond cap.
 		b.off = c // So tryGrowByReslice returns false.
-		b.grow(int(size) - l)
-		b.buf = b.buf[:int(size)]
+		b.grow(n - l)
+		b.buf = b.buf[:n]
 
 	default:
 		// Reduce the size of the buffer.
-		zeroOutSlice(b.buf[size:])
-		b.buf = b.buf[:size]
+		b.cut(size)
+		b.invalidate(b.Base(), size)
+		zeroOutSlice(b.buf[n:])
+		b.buf = b.buf[:n]
 	}
 
 	b.off = prev
 	if b.flag&os.O_APPEND != 0 {
-		b.off = int(size)
+		b.off = n
 	}
 
 	return nil
@@ -373,15 +484,16 @@ func (b *Buffer) Grow(n int) {
 		panic("flexbuf.Buffer.Grow: negative count")
 	}
 
+	b.lock()
+	defer b.unlock()
+
 	l := len(b.buf)
 	if l+n <= cap(b.buf) {
 		return
 	}
 
 	// Allocate bigger buffer.
-	tmp := makeSlice(l + n)
-	copy(tmp, b.buf)
-	b.buf = tmp
+	b.realloc(l + n)
 	b.buf = b.buf[:l]
 }
 
@@ -394,16 +506,53 @@ func (b *Buffer) grow(n int) {
 	if ok := b.tryGrowByReslice(n); ok {
 		return
 	}
-	if b.buf == nil && n <= smallBufferSize {
+	if b.buf == nil && b.mem == nil && n <= smallBufferSize {
 		b.buf = make([]byte, n, smallBufferSize)
 		return
 	}
 	// Allocate bigger buffer.
-	tmp := makeSlice(cap(b.buf)*2 + n) // cap(b.buf) may be zero.
+	b.realloc(cap(b.buf)*2 + n) // cap(b.buf) may be zero.
+}
+
+// realloc replaces the underlying buffer with a new one of length n
+// preserving its contents. If the buffer can't grow it will panic
+// with ErrTooLarge.
+func (b *Buffer) realloc(n int) {
+	if b.mem != nil {
+		// Shared storage must not be resized in place.
+		reuse := b.mapped && !b.cow
+		if tmp, ok := b.mem.realloc(b.buf, reuse, n); ok {
+			if !reuse {
+				b.drop()
+			}
+			b.buf = tmp
+			b.mapped = true
+			return
+		}
+	}
+	tmp := makeSlice(n)
 	copy(tmp, b.buf)
+	b.drop()
 	b.buf = tmp
 }
 
+// drop drops the reference to the underlying storage releasing it if it was
+// allocated outside of the Go heap and no other buffer uses it. The caller
+// is responsible for replacing b.buf.
+func (b *Buffer) drop() {
+	if b.cow {
+		b.cow = false
+		if !b.unshare() {
+			b.mapped = false
+			return
+		}
+	}
+	if b.mapped {
+		b.mem.free(b.buf)
+		b.mapped = false
+	}
+}
+
 // tryGrowByReslice is a inlineable version of grow for the fast-case where the
 // internal buffer only needs to be resliced. It returns whether it succeeded.
 func (b *Buffer) tryGrowByReslice(n int) bool {
@@ -442,12 +591,13 @@ func zeroOutSlice(b []byte) {
 
 // Offset returns the current offset.
 func (b *Buffer) Offset() int {
-	return b.off
+	return int(b.base) + b.off
 }
 
-// Len returns the number of bytes in the buffer.
+// Len returns the number of bytes in the buffer, discarded bytes are not
+// counted.
 func (b *Buffer) Len() int {
-	return len(b.buf)
+	return len(b.buf) - b.low
 }
 
 // Cap returns the capacity of the buffer's underlying byte slice, that is,
@@ -456,14 +606,29 @@ func (b *Buffer) Cap() int {
 	return cap(b.buf)
 }
 
-// Close sets offset to zero and zero put the buffer. It always returns
-// nil error.
+// Close sets offset to zero and zero put the buffer. Buffers stored outside
+// of the Go heap release their memory and buffers which don't own their
+// data release it instead. It returns ErrUnset when any placeholder
+// returned by Reserve was not set, see Finish.
 func (b *Buffer) Close() error {
 	if b == nil {
 		return nil
 	}
+	b.lock()
+	defer b.unlock()
+	b.end()
+	err := b.finish()
 	b.off = 0
+	b.base = 0
+	b.low = 0
+	b.invalidate(0, -1) // All placeholders.
+	b.runeLen = 0
+	if b.cow || b.mapped {
+		b.drop()
+		b.buf = nil
+		return err
+	}
 	zeroOutSlice(b.buf[0:len(b.buf)])
 	b.buf = b.buf[:0]
-	return nil
+	return err
 }
@@ -0,0 +1,131 @@
+package flexbuf
+
+import (
+	"io"
+)
+
+// flushChunk is the maximum number of bytes written back with single
+// WriteAt call.
+const flushChunk = 1 << 20
+
+// ReaderWriterAt is the interface that groups the ReadAt and WriteAt
+// methods.
+type ReaderWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// MaxDirty is the Paged constructor option setting the number of modified
+// bytes which, when exceeded, trigger the write-back of all modified
+// bytes. Zero means modified bytes are written back only by Flush and
+// Sync methods.
+func MaxDirty(n int64) func(*Paged) {
+	return func(pg *Paged) {
+		pg.maxDirty = n
+	}
+}
+
+// NewCache returns new Paged buffer caching f of given size. Reads and
+// writes hit memory, modified bytes are written back to f by Flush or
+// Sync methods or when the MaxDirty threshold is exceeded. When f is
+// an *os.File or implements Truncate(size int64) error and Sync() error
+// methods they are used by Flush and Sync methods accordingly.
+func NewCache(f ReaderWriterAt, size int64, opts ...func(*Paged)) *Paged {
+	pg := NewPaged(f, size, opts...)
+	pg.dst = f
+	return pg
+}
+
+// Dirty returns the byte ranges modified since the last write-back.
+func (pg *Paged) Dirty() []Range {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return append([]Range(nil), pg.dirty...)
+}
+
+// Flush writes the modified byte ranges back to the destination and marks
+// all pages as clean. Adjacent modifications are coalesced and written with
+// as few WriteAt calls as possible. When the buffer was truncated and the
+// destination implements Truncate method it's truncated to the buffer
+// size. Flush is a no-op for buffers created with NewPaged.
+func (pg *Paged) Flush() error {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return pg.flush()
+}
+
+// flush writes the modified byte ranges back to the destination. See Flush.
+func (pg *Paged) flush() error {
+	if pg.dst == nil {
+		return nil
+	}
+
+	// Number of bytes the destination holds.
+	held := pg.srcSize
+	if pg.truncated {
+		if t, ok := pg.dst.(interface{ Truncate(int64) error }); ok {
+			if err := t.Truncate(pg.size); err != nil {
+				return err
+			}
+			held = pg.size
+		}
+		pg.truncated = false
+	}
+
+	var tmp []byte
+	for len(pg.dirty) > 0 {
+		r := pg.dirty[0]
+		for r.Len > 0 {
+			n := r.Len
+			if n > flushChunk {
+				n = flushChunk
+			}
+			if int64(cap(tmp)) < n {
+				tmp = make([]byte, n)
+			}
+			if _, err := pg.readAt(tmp[:n], r.Off, false); err != nil {
+				return err
+			}
+			if _, err := pg.dst.WriteAt(tmp[:n], r.Off); err != nil {
+				return err
+			}
+			if end := r.Off + n; end > held {
+				held = end
+			}
+			r.Off += n
+			r.Len -= n
+			pg.dirty[0] = r
+		}
+		pg.dirty = pg.dirty[1:]
+	}
+	pg.dirty = nil
+
+	for _, pp := range pg.pages {
+		if pp.dirty {
+			pp.dirty = false
+			pp.elem = pg.lru.PushFront(pp)
+		}
+	}
+	pg.evict()
+
+	// Bytes beyond the destination size read as zeros, when the buffer
+	// was extended and the destination can't be truncated the zero tail
+	// is not written.
+	pg.srcSize = held
+
+	return nil
+}
+
+// Sync calls Flush and then commits the destination contents to stable
+// storage if it implements Sync() error method.
+func (pg *Paged) Sync() error {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	if err := pg.flush(); err != nil {
+		return err
+	}
+	if s, ok := pg.dst.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package flexbuf
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingFile records WriteAt calls made to the underlying file.
+type recordingFile struct {
+	ReaderWriterAt
+	writes []Range
+	err    error
+}
+
+func (r *recordingFile) WriteAt(p []byte, off int64) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	r.writes = append(r.writes, Range{Off: off, Len: int64(len(p))})
+	return r.ReaderWriterAt.WriteAt(p, off)
+}
+
+func Test_Cache_Flush(t *testing.T) {
+	// --- Given ---
+	fil := TempFile(t, os.O_RDWR, testData(100))
+	rec := &recordingFile{ReaderWriterAt: fil}
+	pg := NewCache(rec, 100, PageSize(10))
+
+	_, err := pg.WriteAt([]byte{200, 201}, 8)
+	require.NoError(t, err)
+	_, err = pg.WriteAt([]byte{202, 203}, 10)
+	require.NoError(t, err)
+	_, err = pg.WriteAt([]byte{204}, 50)
+	require.NoError(t, err)
+
+	// --- When ---
+	err = pg.Flush()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []Range{{8, 4}, {50, 1}}, rec.writes)
+	assert.Empty(t, pg.Dirty())
+
+	got, err := ioutil.ReadFile(fil.Name())
+	assert.NoError(t, err)
+	exp := testData(100)
+	copy(exp[8:], []byte{200, 201, 202, 203})
+	exp[50] = 204
+	assert.Exactly(t, exp, got)
+}
+
+func Test_Cache_Flush_MarksPagesClean(t *testing.T) {
+	// --- Given ---
+	fil := TempFile(t, os.O_RDWR, testData(100))
+	pg := NewCache(fil, 100, PageSize(10), MaxPages(1))
+
+	for i := int64(0); i < 5; i++ {
+		_, err := pg.WriteAt([]byte{255}, i*10)
+		require.NoError(t, err)
+	}
+	require.Exactly(t, 5, pg.Pages())
+
+	// --- When ---
+	err := pg.Flush()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 1, pg.Pages())
+}
+
+func Test_Cache_Flush_Extend(t *testing.T) {
+	// --- Given ---
+	fil := TempFile(t, os.O_RDWR, []byte{0, 1, 2})
+	pg := NewCache(fil, 3, PageSize(4))
+
+	// --- When ---
+	_, err := pg.WriteAt([]byte{9, 9}, 6)
+	require.NoError(t, err)
+	err = pg.Sync()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	got, err := ioutil.ReadFile(fil.Name())
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1, 2, 0, 0, 0, 9, 9}, got)
+}
+
+func Test_Cache_Flush_ExtendNoTruncate(t *testing.T) {
+	// --- Given ---
+	dst := &recordingFile{ReaderWriterAt: With([]byte{0, 1, 2})}
+	pg := NewCache(dst, 3, PageSize(4), MaxPages(1))
+	require.NoError(t, pg.Truncate(12))
+	_, err := pg.WriteAt([]byte{9}, 5)
+	require.NoError(t, err)
+
+	// --- When ---
+	err = pg.Flush()
+
+	// --- Then ---
+	require.NoError(t, err)
+	assert.Exactly(t, int64(6), pg.srcSize)
+
+	// Evicted pages are fetched again.
+	got := make([]byte, 12)
+	for i := range got {
+		_, err = pg.ReadAt(got[i:i+1], int64(i))
+		require.NoError(t, err)
+	}
+	assert.Exactly(t, []byte{0, 1, 2, 0, 0, 9, 0, 0, 0, 0, 0, 0}, got)
+	assert.Exactly(t, int64(12), pg.Size())
+}
+
+func Test_Cache_Flush_Truncate(t *testing.T) {
+	// --- Given ---
+	fil := TempFile(t, os.O_RDWR, testData(100))
+	pg := NewCache(fil, 100, PageSize(10))
+
+	_, err := pg.WriteAt([]byte{200, 201}, 90)
+	require.NoError(t, err)
+
+	// --- When ---
+	require.NoError(t, pg.Truncate(5))
+	err = pg.Flush()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	got, err := ioutil.ReadFile(fil.Name())
+	assert.NoError(t, err)
+	assert.Exactly(t, testData(5), got)
+}
+
+func Test_Cache_Flush_Error(t *testing.T) {
+	// --- Given ---
+	fil := TempFile(t, os.O_RDWR, testData(100))
+	rec := &recordingFile{ReaderWriterAt: fil, err: errors.New("test")}
+	pg := NewCache(rec, 100, PageSize(10))
+
+	_, err := pg.WriteAt([]byte{200, 201}, 8)
+	require.NoError(t, err)
+
+	// --- When ---
+	err = pg.Flush()
+
+	// --- Then ---
+	assert.EqualError(t, err, "test")
+	assert.Exactly(t, []Range{{8, 2}}, pg.Dirty())
+}
+
+func Test_Cache_MaxDirty(t *testing.T) {
+	// --- Given ---
+	fil := TempFile(t, os.O_RDWR, testData(100))
+	rec := &recordingFile{ReaderWriterAt: fil}
+	pg := NewCache(rec, 100, PageSize(10), MaxDirty(4))
+
+	// --- When ---
+	_, err := pg.WriteAt([]byte{1, 1}, 0)
+	require.NoError(t, err)
+	_, err = pg.WriteAt([]byte{2, 2}, 20)
+	require.NoError(t, err)
+	require.Empty(t, rec.writes)
+	_, err = pg.WriteAt([]byte{3}, 40)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []Range{{0, 2}, {20, 2}, {40, 1}}, rec.writes)
+	assert.Empty(t, pg.Dirty())
+}
+
+func Test_Paged_Flush_NoDestination(t *testing.T) {
+	// --- Given ---
+	pg := NewPaged(bytes.NewReader(testData(10)), 10)
+	_, err := pg.WriteAt([]byte{1}, 0)
+	require.NoError(t, err)
+
+	// --- When ---
+	err = pg.Flush()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []Range{{0, 1}}, pg.Dirty())
+}
@@ -0,0 +1,177 @@
+//go:build linux && (amd64 || arm64 || loong64 || ppc64 || ppc64le || riscv64)
+// +build linux
+// +build amd64 arm64 loong64 ppc64 ppc64le riscv64
+
+package flexbuf
+
+import (
+	"runtime"
+	"sync/atomic"
+	"syscall"
+)
+
+// cloneMapMin is the minimal capacity of the buffer cloned by mapping the
+// snapshot of its contents. Smaller buffers share the storage on the Go
+// heap and copy it on the first write.
+const cloneMapMin = 64 << 10
+
+// cloneMapped clones the buffer to c using private memory mappings of the
+// snapshot of its contents so the kernel copies only the modified pages.
+// The snapshot is made once, later clones of the buffer which was not
+// modified map the same snapshot. It returns false when the buffer is too
+// small or the snapshot can't be made.
+func (b *Buffer) cloneMapped(c *Buffer) bool {
+	a, ok := b.mem.(*snapAlloc)
+	if !ok || a.s == nil || !b.clean {
+		if cap(b.buf) < cloneMapMin {
+			return false
+		}
+		s, err := newSnapshot(b.buf, cap(b.buf))
+		if err != nil {
+			return false
+		}
+		defer s.release()
+
+		a, err = newSnapAlloc(origAlloc(b.mem), s, cap(b.buf))
+		if err != nil {
+			return false
+		}
+		l := len(b.buf)
+		b.drop()
+		b.buf = a.p[:l]
+		b.mem = a
+		b.mapped = true
+		b.clean = true
+	}
+
+	ca, err := newSnapAlloc(a.orig, a.s, cap(b.buf))
+	if err != nil {
+		return false
+	}
+	c.buf = ca.p[:len(b.buf)]
+	c.mem = ca
+	c.mapped = true
+	c.clean = true
+	return true
+}
+
+// snapshot is the memory file with the contents of the cloned buffer. It's
+// never modified, buffers map it privately.
+type snapshot struct {
+	// Memory file descriptor.
+	fd int
+	// Number of mappings and references.
+	refs int32
+}
+
+// newSnapshot returns new snapshot of given size with contents of p.
+func newSnapshot(p []byte, size int) (*snapshot, error) {
+	fd, err := memfdCreate("flexbuf-clone", mfdCloexec)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &snapshot{fd: int(fd), refs: 1}
+	if err = syscall.Ftruncate(s.fd, int64(size)); err != nil {
+		s.release()
+		return nil, err
+	}
+	for off := 0; off < len(p); {
+		n, err := syscall.Pwrite(s.fd, p[off:], int64(off))
+		if err != nil {
+			s.release()
+			return nil, err
+		}
+		off += n
+	}
+	return s, nil
+}
+
+// release releases the reference to the snapshot closing the memory file
+// when it was the last one.
+func (s *snapshot) release() {
+	if atomic.AddInt32(&s.refs, -1) == 0 {
+		_ = syscall.Close(s.fd)
+	}
+}
+
+// snapAlloc is the allocator of the buffer using private mapping of the
+// snapshot. The mapping can't grow, when the buffer grows its contents
+// are moved to memory allocated by orig allocator or to the Go heap.
+type snapAlloc struct {
+	// Allocator used by the buffer before it was cloned.
+	orig allocator
+	// Mapped snapshot, nil after the mapping was released.
+	s *snapshot
+	// Private mapping of the snapshot.
+	p []byte
+}
+
+// newSnapAlloc returns new allocator with private mapping of n bytes of
+// snapshot s. The mapping is released when the allocator is garbage
+// collected so buffers cloned from the Go heap buffers don't have to be
+// closed.
+func newSnapAlloc(orig allocator, s *snapshot, n int) (*snapAlloc, error) {
+	p, err := mmapFd(n, s.fd, syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&s.refs, 1)
+
+	a := &snapAlloc{orig: orig, s: s, p: p}
+	runtime.SetFinalizer(a, (*snapAlloc).release)
+	return a, nil
+}
+
+func (a *snapAlloc) realloc(p []byte, mapped bool, n int) ([]byte, bool) {
+	if a.s == nil {
+		if a.orig == nil {
+			return nil, false
+		}
+		return a.orig.realloc(p, mapped, n)
+	}
+
+	if a.orig != nil {
+		if tmp, ok := a.orig.realloc(p, false, n); ok {
+			a.release()
+			return tmp, true
+		}
+	}
+	return nil, false
+}
+
+func (a *snapAlloc) free(p []byte) {
+	if a.s != nil {
+		a.release()
+		return
+	}
+	if a.orig != nil {
+		a.orig.free(p)
+	}
+}
+
+func (a *snapAlloc) shareable() bool {
+	if a.s == nil && a.orig != nil {
+		return a.orig.shareable()
+	}
+	return true
+}
+
+// release unmaps the snapshot.
+func (a *snapAlloc) release() {
+	if a.s == nil {
+		return
+	}
+	_ = munmap(a.p)
+	a.s.release()
+	a.s = nil
+	a.p = nil
+}
+
+// origAlloc returns the allocator used by the buffer before it was cloned.
+func origAlloc(m allocator) allocator {
+	if a, ok := m.(*snapAlloc); ok {
+		return a.orig
+	}
+	return m
+}
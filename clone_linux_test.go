@@ -0,0 +1,142 @@
+//go:build linux && (amd64 || arm64 || loong64 || ppc64 || ppc64le || riscv64)
+// +build linux
+// +build amd64 arm64 loong64 ppc64 ppc64le riscv64
+
+package flexbuf
+
+import (
+	"io/ioutil"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openFds returns the number of open file descriptors.
+func openFds(t *testing.T) int {
+	t.Helper()
+	fis, err := ioutil.ReadDir("/proc/self/fd")
+	require.NoError(t, err)
+	return len(fis)
+}
+
+func Test_Buffer_Clone_Mapped(t *testing.T) {
+	// --- Given ---
+	data := testData(1 << 20)
+	buf := With(append([]byte(nil), data...), Offset(10))
+
+	// --- When ---
+	c1 := buf.Clone()
+	c2 := buf.Clone()
+
+	// --- Then ---
+	assert.True(t, buf.mapped)
+	assert.True(t, c1.mapped)
+	assert.True(t, c1.mem.(*snapAlloc).s == c2.mem.(*snapAlloc).s)
+	assert.Exactly(t, 10, c1.Offset())
+	assert.Exactly(t, data, c2.buf)
+
+	_, err := c1.WriteAt([]byte{0xff}, 1)
+	require.NoError(t, err)
+	assert.Exactly(t, byte(0xff), c1.buf[1])
+	assert.Exactly(t, data[1], c2.buf[1])
+	assert.Exactly(t, data[1], buf.buf[1])
+
+	_, err = buf.WriteAt([]byte{0xee}, 2)
+	require.NoError(t, err)
+	assert.Exactly(t, data[2], c1.buf[2])
+	assert.Exactly(t, data[2], c2.buf[2])
+
+	// Clone of the modified buffer makes new snapshot.
+	c3 := buf.Clone()
+	assert.False(t, c3.mem.(*snapAlloc).s == c2.mem.(*snapAlloc).s)
+	assert.Exactly(t, byte(0xee), c3.buf[2])
+
+	// Clone of the unmodified clone reuses the snapshot.
+	c4 := c2.Clone()
+	assert.True(t, c4.mem.(*snapAlloc).s == c2.mem.(*snapAlloc).s)
+	assert.Exactly(t, data, c4.buf)
+
+	for _, b := range []*Buffer{buf, c1, c2, c3, c4} {
+		require.NoError(t, b.Close())
+	}
+}
+
+func Test_Buffer_Clone_Mapped_CopiesPages(t *testing.T) {
+	// --- Given ---
+	buf := With(testData(1 << 20))
+	c := buf.Clone()
+	require.NoError(t, c.Close())
+	fds := openFds(t)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// --- When ---
+	for i := 0; i < 100; i++ {
+		c := buf.Clone()
+		_, err := c.WriteAt([]byte{1}, 1000)
+		require.NoError(t, err)
+		require.NoError(t, c.Close())
+	}
+
+	// --- Then ---
+	runtime.ReadMemStats(&after)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(100<<10))
+	assert.Exactly(t, fds, openFds(t))
+	require.NoError(t, buf.Close())
+}
+
+func Test_Buffer_Clone_Mapped_Grow(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		opts []func(*Buffer)
+	}{
+		{"heap", nil},
+		{"off heap", []func(*Buffer){OffHeap(1)}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			data := testData(cloneMapMin)
+			buf := New(tc.opts...)
+			_, _ = buf.Write(data)
+			buf.Grow(cloneMapMin - buf.Len())
+			c := buf.Clone()
+
+			// --- When ---
+			_, err := c.WriteAt([]byte{1, 2}, int64(c.Cap()))
+
+			// --- Then ---
+			require.NoError(t, err)
+			assert.Exactly(t, data, c.buf[:len(data)])
+			assert.Exactly(t, data, buf.buf)
+			assert.Nil(t, c.mem.(*snapAlloc).s)
+			require.NoError(t, c.Close())
+			require.NoError(t, buf.Close())
+		})
+	}
+}
+
+func Test_Buffer_Clone_Mapped_Release(t *testing.T) {
+	// --- Given ---
+	buf := With(testData(cloneMapMin))
+	c := buf.Clone()
+	a := c.mem.(*snapAlloc)
+	s := a.s
+	require.Exactly(t, int32(2), s.refs)
+
+	// --- When ---
+	a.release() // As called by the finalizer of not closed clone.
+	a.release()
+
+	// --- Then ---
+	assert.Nil(t, a.s)
+	assert.Exactly(t, int32(1), atomic.LoadInt32(&s.refs))
+	require.NoError(t, buf.Close())
+	assert.Exactly(t, int32(0), atomic.LoadInt32(&s.refs))
+}
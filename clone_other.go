@@ -0,0 +1,10 @@
+//go:build !linux || !(amd64 || arm64 || loong64 || ppc64 || ppc64le || riscv64)
+// +build !linux !amd64,!arm64,!loong64,!ppc64,!ppc64le,!riscv64
+
+package flexbuf
+
+// cloneMapped always returns false, on this platform clones share the
+// storage on the Go heap.
+func (b *Buffer) cloneMapped(c *Buffer) bool {
+	return false
+}
@@ -0,0 +1,421 @@
+package flexbuf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Content is a named Buffer with modification time and strong ETag which
+// can be served over HTTP. The ETag is the SHA-256 hash of the contents,
+// it's updated incrementally when data is appended and recomputed when
+// already hashed data is modified. Bytes discarded from the buffer with
+// Update are not part of the content, the remaining ones are served from
+// the beginning. Content is safe for concurrent use,
+// any number of requests may be served while it's being written to.
+type Content struct {
+	// Guards the buffer, modification time and hash reset.
+	mu sync.RWMutex
+	// Content data.
+	buf *Buffer
+	// Content name.
+	name string
+	// Modification time.
+	mod time.Time
+	// Guards hash state when updated by readers.
+	hmu sync.Mutex
+	// Hash of the first hashed not discarded bytes of the buffer.
+	hash hash.Hash
+	// Number of hashed bytes.
+	hashed int
+	// Snapshot served to requests, nil when the content changed since
+	// it was taken.
+	snap *contentSnap
+}
+
+// NewContent returns new Content with given name and data. If buf is nil
+// an empty buffer is used. The buffer must not be used directly after
+// this call, use Update method to make arbitrary changes to it.
+func NewContent(name string, buf *Buffer) *Content {
+	if buf == nil {
+		buf = &Buffer{}
+	}
+	return &Content{
+		buf:  buf,
+		name: name,
+		mod:  time.Now(),
+		hash: sha256.New(),
+	}
+}
+
+// Name returns the content name.
+func (c *Content) Name() string {
+	return c.name
+}
+
+// ModTime returns the content modification time.
+func (c *Content) ModTime() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mod
+}
+
+// SetModTime sets the content modification time.
+func (c *Content) SetModTime(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mod = t
+}
+
+// Size returns the content size, that is the number of not discarded bytes.
+func (c *Content) Size() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return int64(c.buf.Len())
+}
+
+// ETag returns quoted strong entity tag of the content.
+func (c *Content) ETag() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.etag()
+}
+
+// etag returns quoted strong entity tag of the content. It must be called
+// with at least read lock held.
+func (c *Content) etag() string {
+	c.hmu.Lock()
+	defer c.hmu.Unlock()
+
+	if data := c.buf.buf[c.buf.low:]; c.hashed < len(data) {
+		_, _ = c.hash.Write(data[c.hashed:])
+		c.hashed = len(data)
+	}
+	return `"` + hex.EncodeToString(c.hash.Sum(nil)) + `"`
+}
+
+// changed must be called with write lock held after bytes at offset off
+// and above were changed.
+func (c *Content) changed(off int64) {
+	c.mod = time.Now()
+	if c.snap != nil {
+		c.snap.release()
+		c.snap = nil
+	}
+	if off < c.buf.Base()+int64(c.hashed) {
+		c.hash.Reset()
+		c.hashed = 0
+	}
+}
+
+// Write appends the contents of p to the content. It returns the number
+// of bytes written; err is always nil.
+func (c *Content) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	off := c.buf.Size()
+	c.changed(off)
+	return c.buf.WriteAt(p, off)
+}
+
+// WriteAt writes len(p) bytes to the content starting at byte offset off.
+// Like ReadAt and Truncate it uses the buffer offsets so discarded bytes
+// are counted. It returns the number of bytes written and ErrOutOfBounds
+// when off points to discarded bytes.
+func (c *Content) WriteAt(p []byte, off int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed(off)
+	return c.buf.WriteAt(p, off)
+}
+
+// ReadAt reads len(p) bytes from the content starting at byte offset off.
+// It returns the number of bytes read and the error, if any. ReadAt
+// always returns a non-nil error when n < len(p).
+func (c *Content) ReadAt(p []byte, off int64) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.buf.ReadAt(p, off)
+}
+
+// Truncate changes the size of the content.
+func (c *Content) Truncate(size int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.buf.Truncate(size); err != nil {
+		return err
+	}
+	c.changed(size)
+	return nil
+}
+
+// Update calls fn with the underlying buffer while holding exclusive
+// lock. The content is considered changed after the call and its ETag
+// is recomputed.
+func (c *Content) Update(fn func(buf *Buffer) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changed(0)
+	return fn(c.buf)
+}
+
+// ServeHTTP serves the content using http.ServeContent with ETag header
+// set so Range, If-Range, If-Match and If-None-Match request headers are
+// honored. Responses are served from the snapshot of the content taken
+// together with its ETag so writes made while they are sent are not
+// visible in them. The snapshot is taken by the first request after the
+// content changed and is shared by the following requests. It's made
+// with Buffer.Clone, so the first write after it copies the whole content
+// or, for contents of at least 64 KiB on Linux, taking it copies the
+// content to the memory file and writes copy only the modified pages.
+func (c *Content) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	snap, mod := c.snapshot()
+	defer snap.release()
+
+	w.Header().Set("ETag", snap.etag)
+	rs := io.NewSectionReader(snap.buf, snap.buf.Base(), int64(snap.buf.Len()))
+	http.ServeContent(w, r, c.name, mod, rs)
+}
+
+// snapshot returns the content snapshot and modification time. The caller
+// must release the snapshot.
+func (c *Content) snapshot() (*contentSnap, time.Time) {
+	c.mu.RLock()
+	if snap := c.snap; snap != nil {
+		atomic.AddInt32(&snap.refs, 1)
+		mod := c.mod
+		c.mu.RUnlock()
+		return snap, mod
+	}
+	c.mu.RUnlock()
+
+	// Clone changes the buffer so it must not be read at the same time.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snap == nil {
+		c.snap = &contentSnap{
+			buf:  c.buf.Clone(),
+			etag: c.etag(),
+			refs: 1,
+		}
+	}
+	atomic.AddInt32(&c.snap.refs, 1)
+	return c.snap, c.mod
+}
+
+// contentSnap is the snapshot of the content shared by requests.
+type contentSnap struct {
+	// Clone of the content buffer.
+	buf *Buffer
+	// Quoted strong entity tag of the snapshot.
+	etag string
+	// Number of references, including the one held by Content.
+	refs int32
+}
+
+// release releases the reference to the snapshot closing its buffer
+// when it was the last one.
+func (s *contentSnap) release() {
+	if atomic.AddInt32(&s.refs, -1) == 0 {
+		_ = s.buf.Close()
+	}
+}
+
+// Stat returns os.FileInfo describing the content.
+func (c *Content) Stat() os.FileInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stat()
+}
+
+// stat returns os.FileInfo describing the content. It must be called with
+// at least read lock held.
+func (c *Content) stat() os.FileInfo {
+	return fileInfo{
+		name: path.Base(c.name),
+		size: int64(c.buf.Len()),
+		mod:  c.mod,
+	}
+}
+
+// open returns http.File reading the content.
+func (c *Content) open() *contentFile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	fi := c.stat()
+	return &contentFile{
+		SectionReader: io.NewSectionReader(c, c.buf.Base(), fi.Size()),
+		fi:            fi,
+	}
+}
+
+// FileSystem is a collection of named Contents implementing http.Handler
+// and http.FileSystem interfaces. Directories are implied by the content
+// names, for example content named "/a/b.txt" makes "/a" a directory.
+// FileSystem is safe for concurrent use.
+type FileSystem struct {
+	// Guards files.
+	mu sync.RWMutex
+	// Contents by their clean absolute names.
+	files map[string]*Content
+}
+
+// NewFileSystem returns new FileSystem with given contents.
+func NewFileSystem(cs ...*Content) *FileSystem {
+	fs := &FileSystem{files: make(map[string]*Content, len(cs))}
+	for _, c := range cs {
+		fs.Add(c)
+	}
+	return fs
+}
+
+// Add adds content to the file system replacing the one with the same name.
+func (fs *FileSystem) Add(c *Content) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.files[cleanName(c.Name())] = c
+}
+
+// Remove removes content with given name from the file system.
+func (fs *FileSystem) Remove(name string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	delete(fs.files, cleanName(name))
+}
+
+// Get returns content with given name or nil if it doesn't exist.
+func (fs *FileSystem) Get(name string) *Content {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.files[cleanName(name)]
+}
+
+// ServeHTTP serves content named by the request URL path.
+// It responds with 404 status when the content doesn't exist.
+func (fs *FileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c := fs.Get(r.URL.Path)
+	if c == nil {
+		http.NotFound(w, r)
+		return
+	}
+	c.ServeHTTP(w, r)
+}
+
+// Open implements http.FileSystem interface.
+func (fs *FileSystem) Open(name string) (http.File, error) {
+	name = cleanName(name)
+
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	if c, ok := fs.files[name]; ok {
+		return c.open(), nil
+	}
+
+	// Directory entries.
+	prefix := name
+	if prefix != "/" {
+		prefix += "/"
+	}
+	seen := make(map[string]bool)
+	var ents []os.FileInfo
+	for n, c := range fs.files {
+		if !strings.HasPrefix(n, prefix) {
+			continue
+		}
+		rest := n[len(prefix):]
+		if i := strings.IndexByte(rest, '/'); i >= 0 {
+			if dir := rest[:i]; !seen[dir] {
+				seen[dir] = true
+				ents = append(ents, fileInfo{name: dir, dir: true})
+			}
+			continue
+		}
+		ents = append(ents, c.Stat())
+	}
+
+	if len(ents) == 0 && name != "/" {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	sort.Slice(ents, func(i, j int) bool { return ents[i].Name() < ents[j].Name() })
+
+	return &dirFile{
+		fi:   fileInfo{name: path.Base(name), dir: true},
+		ents: ents,
+	}, nil
+}
+
+// cleanName returns clean absolute content name.
+func cleanName(name string) string {
+	return path.Clean("/" + name)
+}
+
+// fileInfo implements os.FileInfo interface.
+type fileInfo struct {
+	name string
+	size int64
+	mod  time.Time
+	dir  bool
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return fi.mod }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+func (fi fileInfo) Mode() os.FileMode {
+	if fi.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+// contentFile implements http.File interface for Content.
+type contentFile struct {
+	*io.SectionReader
+	fi os.FileInfo
+}
+
+func (f *contentFile) Close() error                       { return nil }
+func (f *contentFile) Stat() (os.FileInfo, error)         { return f.fi, nil }
+func (f *contentFile) Readdir(int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+
+// dirFile implements http.File interface for FileSystem directories.
+type dirFile struct {
+	fi   os.FileInfo
+	ents []os.FileInfo
+	// Number of entries returned by Readdir.
+	pos int
+}
+
+func (f *dirFile) Close() error                   { return nil }
+func (f *dirFile) Stat() (os.FileInfo, error)     { return f.fi, nil }
+func (f *dirFile) Read([]byte) (int, error)       { return 0, os.ErrInvalid }
+func (f *dirFile) Seek(int64, int) (int64, error) { return 0, os.ErrInvalid }
+
+// Readdir implements http.File interface.
+func (f *dirFile) Readdir(n int) ([]os.FileInfo, error) {
+	rem := f.ents[f.pos:]
+	if n <= 0 {
+		f.pos = len(f.ents)
+		return rem, nil
+	}
+	if len(rem) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rem) {
+		n = len(rem)
+	}
+	f.pos += n
+	return rem[:n], nil
+}
@@ -0,0 +1,476 @@
+package flexbuf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sha256ETag returns quoted hex encoded SHA-256 of data.
+func sha256ETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func Test_Content_ETag(t *testing.T) {
+	// --- Given ---
+	c := NewContent("data.bin", With([]byte{0, 1, 2}))
+
+	// --- Then ---
+	assert.Exactly(t, sha256ETag([]byte{0, 1, 2}), c.ETag())
+	assert.Exactly(t, "data.bin", c.Name())
+	assert.Exactly(t, int64(3), c.Size())
+}
+
+func Test_Content_ETag_Changes(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		fn  func(c *Content)
+		exp []byte
+	}{
+		{
+			"append",
+			func(c *Content) { _, _ = c.Write([]byte{3, 4}) },
+			[]byte{0, 1, 2, 3, 4},
+		},
+		{
+			"overwrite",
+			func(c *Content) { _, _ = c.WriteAt([]byte{9}, 1) },
+			[]byte{0, 9, 2},
+		},
+		{
+			"write beyond end",
+			func(c *Content) { _, _ = c.WriteAt([]byte{9}, 4) },
+			[]byte{0, 1, 2, 0, 9},
+		},
+		{
+			"truncate",
+			func(c *Content) { _ = c.Truncate(1) },
+			[]byte{0},
+		},
+		{
+			"update",
+			func(c *Content) {
+				_ = c.Update(func(buf *Buffer) error {
+					buf.buf[0] = 7
+					return nil
+				})
+			},
+			[]byte{7, 1, 2},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			c := NewContent("data.bin", With([]byte{0, 1, 2}))
+			c.SetModTime(time.Time{})
+			_ = c.ETag() // Hash current data.
+
+			// --- When ---
+			tc.fn(c)
+
+			// --- Then ---
+			assert.Exactly(t, sha256ETag(tc.exp), c.ETag())
+			assert.False(t, c.ModTime().IsZero())
+		})
+	}
+}
+
+func Test_Content_ServeHTTP(t *testing.T) {
+	// --- Given ---
+	data := testData(1000)
+	c := NewContent("data.bin", With(data))
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	// --- When ---
+	rsp, err := srv.Client().Get(srv.URL)
+
+	// --- Then ---
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	got, err := ioutil.ReadAll(rsp.Body)
+	assert.NoError(t, err)
+	assert.Exactly(t, http.StatusOK, rsp.StatusCode)
+	assert.Exactly(t, data, got)
+	assert.Exactly(t, sha256ETag(data), rsp.Header.Get("ETag"))
+	assert.Exactly(t, "bytes", rsp.Header.Get("Accept-Ranges"))
+	assert.NotEmpty(t, rsp.Header.Get("Last-Modified"))
+}
+
+func Test_Content_ServeHTTP_Conditional(t *testing.T) {
+	// --- Given ---
+	data := testData(1000)
+	etag := sha256ETag(data)
+
+	tt := []struct {
+		testN string
+
+		hdr    map[string]string
+		status int
+		exp    []byte
+	}{
+		{
+			"range",
+			map[string]string{"Range": "bytes=10-19"},
+			http.StatusPartialContent,
+			data[10:20],
+		},
+		{
+			"if-range match",
+			map[string]string{"Range": "bytes=10-19", "If-Range": etag},
+			http.StatusPartialContent,
+			data[10:20],
+		},
+		{
+			"if-range mismatch",
+			map[string]string{"Range": "bytes=10-19", "If-Range": `"other"`},
+			http.StatusOK,
+			data,
+		},
+		{
+			"if-none-match",
+			map[string]string{"If-None-Match": etag},
+			http.StatusNotModified,
+			[]byte{},
+		},
+		{
+			"if-match mismatch",
+			map[string]string{"If-Match": `"other"`},
+			http.StatusPreconditionFailed,
+			[]byte{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			c := NewContent("data.bin", With(data))
+			req := httptest.NewRequest(http.MethodGet, "/data.bin", nil)
+			for k, v := range tc.hdr {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			// --- When ---
+			c.ServeHTTP(rec, req)
+
+			// --- Then ---
+			assert.Exactly(t, tc.status, rec.Code)
+			assert.Exactly(t, string(tc.exp), rec.Body.String())
+		})
+	}
+}
+
+func Test_Content_Discarded(t *testing.T) {
+	// --- Given ---
+	c := NewContent("/data.bin", With([]byte("0123456789")))
+	_ = c.ETag()
+	require.NoError(t, c.Update(func(buf *Buffer) error {
+		return buf.Discard(6)
+	}))
+
+	// --- When ---
+	n, err := c.Write([]byte("ab"))
+
+	// --- Then ---
+	require.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, int64(6), c.Size())
+	assert.Exactly(t, sha256ETag([]byte("6789ab")), c.ETag())
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data.bin", nil))
+	assert.Exactly(t, http.StatusOK, rec.Code)
+	assert.Exactly(t, "6789ab", rec.Body.String())
+
+	f, err := NewFileSystem(c).Open("/data.bin")
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Exactly(t, "6789ab", string(got))
+}
+
+// hookRecorder is httptest.ResponseRecorder calling fn before the first
+// write to the body.
+type hookRecorder struct {
+	*httptest.ResponseRecorder
+	fn func()
+}
+
+func (r *hookRecorder) Write(p []byte) (int, error) {
+	if r.fn != nil {
+		r.fn()
+		r.fn = nil
+	}
+	return r.ResponseRecorder.Write(p)
+}
+
+func Test_Content_ServeHTTP_Snapshot(t *testing.T) {
+	// --- Given ---
+	data := testData(100000)
+	c := NewContent("data.bin", With(append([]byte(nil), data...)))
+	req := httptest.NewRequest(http.MethodGet, "/data.bin", nil)
+	rec := &hookRecorder{ResponseRecorder: httptest.NewRecorder()}
+	rec.fn = func() {
+		_, err := c.WriteAt(bytes.Repeat([]byte{0xff}, len(data)), 0)
+		require.NoError(t, err)
+	}
+
+	// --- When ---
+	c.ServeHTTP(rec, req)
+
+	// --- Then ---
+	assert.Exactly(t, http.StatusOK, rec.Code)
+	assert.Exactly(t, sha256ETag(data), rec.Header().Get("ETag"))
+	assert.Exactly(t, data, rec.Body.Bytes())
+	assert.Exactly(t, sha256ETag(bytes.Repeat([]byte{0xff}, len(data))), c.ETag())
+}
+
+func Test_Content_ServeHTTP_SnapshotReused(t *testing.T) {
+	// --- Given ---
+	c := NewContent("data.bin", With(testData(100)))
+	req := httptest.NewRequest(http.MethodGet, "/data.bin", nil)
+	c.ServeHTTP(httptest.NewRecorder(), req)
+	snap := c.snap
+
+	// --- When ---
+	c.ServeHTTP(httptest.NewRecorder(), req)
+
+	// --- Then ---
+	assert.True(t, snap == c.snap)
+	assert.Exactly(t, int32(1), snap.refs)
+
+	_, err := c.Write([]byte{1})
+	require.NoError(t, err)
+	assert.Nil(t, c.snap)
+	assert.Exactly(t, int32(0), snap.refs)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	assert.Exactly(t, append(testData(100), 1), rec.Body.Bytes())
+}
+
+func Test_Content_ServeHTTP_ConcurrentLarge(t *testing.T) {
+	// --- Given ---
+	data := testData(1 << 20)
+	c := NewContent("data.bin", With(append([]byte(nil), data...)))
+	req := httptest.NewRequest(http.MethodGet, "/data.bin", nil)
+
+	// --- When ---
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			rec := httptest.NewRecorder()
+			c.ServeHTTP(rec, req)
+			assert.Exactly(t, 1<<20, rec.Body.Len())
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		got := make([]byte, 100)
+		for i := 0; i < 200; i++ {
+			_, err := c.ReadAt(got, int64(i*1000))
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		_, err := c.WriteAt([]byte{byte(i)}, int64(i))
+		require.NoError(t, err)
+	}
+	wg.Wait()
+
+	// --- Then ---
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+	assert.Exactly(t, sha256ETag(rec.Body.Bytes()), rec.Header().Get("ETag"))
+}
+
+func Test_Content_ServeHTTP_Concurrent(t *testing.T) {
+	// --- Given ---
+	c := NewContent("data.bin", nil)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	// --- When ---
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				rsp, err := srv.Client().Get(srv.URL)
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, _ = ioutil.ReadAll(rsp.Body)
+				_ = rsp.Body.Close()
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		_, _ = c.Write(testData(100))
+	}
+	wg.Wait()
+
+	// --- Then ---
+	assert.Exactly(t, int64(10000), c.Size())
+}
+
+func Test_FileSystem_ServeHTTP(t *testing.T) {
+	// --- Given ---
+	fs := NewFileSystem(
+		NewContent("a.txt", With([]byte("aaa"))),
+		NewContent("/dir/b.txt", With([]byte("bbb"))),
+	)
+
+	tt := []struct {
+		testN string
+
+		path   string
+		status int
+		exp    string
+	}{
+		{"root file", "/a.txt", http.StatusOK, "aaa"},
+		{"nested file", "/dir/b.txt", http.StatusOK, "bbb"},
+		{"not existing", "/c.txt", http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rec := httptest.NewRecorder()
+
+			// --- When ---
+			fs.ServeHTTP(rec, req)
+
+			// --- Then ---
+			assert.Exactly(t, tc.status, rec.Code)
+			assert.Exactly(t, tc.exp, rec.Body.String())
+		})
+	}
+}
+
+func Test_FileSystem_AddRemove(t *testing.T) {
+	// --- Given ---
+	fs := NewFileSystem()
+	c := NewContent("a.txt", nil)
+
+	// --- When ---
+	fs.Add(c)
+
+	// --- Then ---
+	assert.Same(t, c, fs.Get("/a.txt"))
+	fs.Remove("a.txt")
+	assert.Nil(t, fs.Get("/a.txt"))
+}
+
+func Test_FileSystem_Open(t *testing.T) {
+	// --- Given ---
+	mod := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := NewContent("/dir/b.txt", With([]byte("bbb")))
+	c.SetModTime(mod)
+	fs := NewFileSystem(NewContent("a.txt", With([]byte("aaa"))), c)
+
+	// --- When ---
+	f, err := fs.Open("/dir/b.txt")
+
+	// --- Then ---
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Exactly(t, "bbb", string(got))
+
+	fi, err := f.Stat()
+	assert.NoError(t, err)
+	assert.Exactly(t, "b.txt", fi.Name())
+	assert.Exactly(t, int64(3), fi.Size())
+	assert.Exactly(t, mod, fi.ModTime())
+	assert.False(t, fi.IsDir())
+	assert.NoError(t, f.Close())
+}
+
+func Test_FileSystem_Open_Dir(t *testing.T) {
+	// --- Given ---
+	fs := NewFileSystem(
+		NewContent("a.txt", nil),
+		NewContent("/dir/b.txt", nil),
+		NewContent("/dir/sub/c.txt", nil),
+	)
+
+	// --- When ---
+	f, err := fs.Open("/")
+
+	// --- Then ---
+	require.NoError(t, err)
+	fi, err := f.Stat()
+	assert.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	ents, err := f.Readdir(-1)
+	assert.NoError(t, err)
+	require.Len(t, ents, 2)
+	assert.Exactly(t, "a.txt", ents[0].Name())
+	assert.False(t, ents[0].IsDir())
+	assert.Exactly(t, "dir", ents[1].Name())
+	assert.True(t, ents[1].IsDir())
+
+	f, err = fs.Open("/dir")
+	require.NoError(t, err)
+	ents, err = f.Readdir(1)
+	assert.NoError(t, err)
+	require.Len(t, ents, 1)
+	assert.Exactly(t, "b.txt", ents[0].Name())
+	ents, err = f.Readdir(1)
+	assert.NoError(t, err)
+	require.Len(t, ents, 1)
+	assert.Exactly(t, "sub", ents[0].Name())
+	_, err = f.Readdir(1)
+	assert.Exactly(t, io.EOF, err)
+}
+
+func Test_FileSystem_Open_NotExist(t *testing.T) {
+	// --- Given ---
+	fs := NewFileSystem(NewContent("a.txt", nil))
+
+	// --- When ---
+	f, err := fs.Open("/b.txt")
+
+	// --- Then ---
+	assert.Nil(t, f)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_FileSystem_FileServer(t *testing.T) {
+	// --- Given ---
+	fs := NewFileSystem(NewContent("a.txt", With([]byte("aaa"))))
+	srv := httptest.NewServer(http.FileServer(fs))
+	defer srv.Close()
+
+	// --- When ---
+	rsp, err := srv.Client().Get(srv.URL + "/a.txt")
+
+	// --- Then ---
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	got, err := ioutil.ReadAll(rsp.Body)
+	assert.NoError(t, err)
+	assert.Exactly(t, http.StatusOK, rsp.StatusCode)
+	assert.Exactly(t, "aaa", string(got))
+}
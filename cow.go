@@ -0,0 +1,100 @@
+package flexbuf
+
+import (
+	"sync/atomic"
+)
+
+// CopyOnWrite is the constructor option making the buffer read directly
+// from the data passed to With without taking its ownership. The private
+// copy of the data is made on the first call mutating the buffer (Write,
+// WriteAt, ReadFrom, Truncate, ...) so the caller's slice is never
+// modified and read-only use doesn't copy the data at all. Calling Close
+// before the copy is made releases the data instead of zeroing it.
+func CopyOnWrite(b *Buffer) {
+	b.cow = true
+}
+
+// share counts buffers sharing the same storage.
+type share struct {
+	refs int32
+}
+
+// Clone returns new Buffer with the same contents, offset and flags. The
+// clone shares the storage with the original buffer. On Linux buffers of
+// at least 64 KiB capacity are cloned by mapping the snapshot of their
+// contents privately, the snapshot is made by the first Clone and later
+// clones of the unmodified buffer reuse it, so writes to either of them
+// copy only the modified pages. Smaller buffers, and all buffers on other
+// platforms, share the Go heap storage which is copied as a whole by the
+// first call mutating either of them. Buffers created with NewMemfd are
+// copied right away.
+//
+// The original and the clone may be used by different goroutines.
+func (b *Buffer) Clone() *Buffer {
+	c := &Buffer{
+		flag: b.flag,
+		off:  b.off,
+		base: b.base,
+		low:  b.low,
+		mem:  b.mem,
+	}
+
+	if b.mem != nil && !b.mem.shareable() {
+		c.mem = nil
+		c.buf = make([]byte, len(b.buf), cap(b.buf))
+		copy(c.buf, b.buf)
+		return c
+	}
+
+	b.lock()
+	mapped := b.cloneMapped(c)
+	b.unlock()
+	if mapped {
+		return c
+	}
+
+	switch {
+	case b.shr != nil:
+		atomic.AddInt32(&b.shr.refs, 1)
+	case !b.cow:
+		b.shr = &share{refs: 2}
+		b.cow = true
+	}
+
+	c.buf = b.buf
+	c.mapped = b.mapped
+	c.cow = b.cow
+	c.shr = b.shr
+	return c
+}
+
+// own makes sure the buffer owns the underlying storage by copying it
+// if needed. The copy has the same length and capacity.
+func (b *Buffer) own() {
+	b.clean = false
+	if !b.cow {
+		return
+	}
+
+	// All the clones stopped using the storage.
+	if b.shr != nil && atomic.LoadInt32(&b.shr.refs) == 1 {
+		b.cow = false
+		b.shr = nil
+		return
+	}
+
+	l := len(b.buf)
+	b.realloc(cap(b.buf))
+	b.buf = b.buf[:l]
+}
+
+// unshare stops sharing the storage with clones. It returns true when no
+// other buffer uses the storage and the buffer became its owner.
+func (b *Buffer) unshare() bool {
+	shr := b.shr
+	if shr == nil {
+		return false // Storage owned by the caller.
+	}
+	b.shr = nil
+	return atomic.AddInt32(&shr.refs, -1) == 0
+}
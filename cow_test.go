@@ -0,0 +1,319 @@
+package flexbuf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CopyOnWrite_Read(t *testing.T) {
+	// --- Given ---
+	data := []byte{0, 1, 2, 3}
+	buf := With(data, CopyOnWrite)
+
+	// --- When ---
+	got, err := ioutil.ReadAll(buf)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, data, got)
+	assert.True(t, buf.cow)
+	assert.True(t, &data[0] == &buf.buf[0])
+}
+
+func Test_CopyOnWrite_Write(t *testing.T) {
+	// --- Given ---
+	data := make([]byte, 4, 8)
+	copy(data, []byte{0, 1, 2, 3})
+	buf := With(data, CopyOnWrite, Offset(2))
+
+	// --- When ---
+	n, err := buf.Write([]byte{4, 5, 6})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 3, n)
+	assert.False(t, buf.cow)
+	assert.Exactly(t, []byte{0, 1, 4, 5, 6}, buf.buf)
+	assert.Exactly(t, []byte{0, 1, 2, 3, 0, 0, 0, 0}, data[:8])
+}
+
+func Test_CopyOnWrite_Mutations(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		fn  func(buf *Buffer) error
+		exp []byte
+	}{
+		{
+			"WriteByte",
+			func(buf *Buffer) error { return buf.WriteByte(9) },
+			[]byte{9, 1, 2, 3},
+		},
+		{
+			"WriteString",
+			func(buf *Buffer) error { _, err := buf.WriteString("a"); return err },
+			[]byte{'a', 1, 2, 3},
+		},
+		{
+			"WriteAt",
+			func(buf *Buffer) error { _, err := buf.WriteAt([]byte{9}, 3); return err },
+			[]byte{0, 1, 2, 9},
+		},
+		{
+			"WriteAt beyond cap",
+			func(buf *Buffer) error { _, err := buf.WriteAt([]byte{9}, 5); return err },
+			[]byte{0, 1, 2, 3, 0, 9},
+		},
+		{
+			"ReadFrom",
+			func(buf *Buffer) error { _, err := buf.ReadFrom(bytes.NewReader([]byte{9})); return err },
+			[]byte{9, 1, 2, 3},
+		},
+		{
+			"Truncate shrink",
+			func(buf *Buffer) error { return buf.Truncate(1) },
+			[]byte{0},
+		},
+		{
+			"Truncate extend",
+			func(buf *Buffer) error { return buf.Truncate(6) },
+			[]byte{0, 1, 2, 3, 0, 0},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			data := []byte{0, 1, 2, 3, 4, 5}[:4]
+			buf := With(data, CopyOnWrite)
+
+			// --- When ---
+			err := tc.fn(buf)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.exp, buf.buf)
+			assert.Exactly(t, []byte{0, 1, 2, 3, 4, 5}, data[:6])
+		})
+	}
+}
+
+func Test_CopyOnWrite_Grow(t *testing.T) {
+	// --- Given ---
+	data := []byte{0, 1, 2, 3}
+	buf := With(data, CopyOnWrite)
+
+	// --- When ---
+	buf.Grow(10)
+
+	// --- Then ---
+	assert.False(t, buf.cow)
+	assert.Exactly(t, 4, buf.Len())
+	assert.Exactly(t, 14, buf.Cap())
+	require.NoError(t, buf.WriteByte(9))
+	assert.Exactly(t, []byte{0, 1, 2, 3}, data)
+}
+
+func Test_CopyOnWrite_Truncate_NoOp(t *testing.T) {
+	// --- Given ---
+	data := []byte{0, 1, 2, 3}
+	buf := With(data, CopyOnWrite)
+	c := buf.Clone()
+
+	// --- When ---
+	err1 := buf.Truncate(buf.Size())
+	err2 := c.Truncate(c.Size())
+
+	// --- Then ---
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.True(t, buf.cow)
+	assert.True(t, c.cow)
+	assert.True(t, &data[0] == &buf.buf[0])
+	assert.True(t, &data[0] == &c.buf[0])
+}
+
+func Test_CopyOnWrite_Close(t *testing.T) {
+	// --- Given ---
+	data := []byte{0, 1, 2, 3}
+	buf := With(data, CopyOnWrite, Offset(2))
+
+	// --- When ---
+	err := buf.Close()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.False(t, buf.cow)
+	assert.Nil(t, buf.buf)
+	assert.Exactly(t, 0, buf.off)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, data)
+}
+
+func Test_CopyOnWrite_Release(t *testing.T) {
+	// --- Given ---
+	data := []byte{0, 1, 2, 3}
+	buf := With(data, CopyOnWrite)
+
+	// --- When ---
+	got := buf.Release()
+
+	// --- Then ---
+	assert.False(t, buf.cow)
+	assert.True(t, &data[0] == &got[0])
+}
+
+func Test_Buffer_Clone(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3}, Append)
+
+	// --- When ---
+	c := buf.Clone()
+
+	// --- Then ---
+	assert.Exactly(t, buf.flag, c.flag)
+	assert.Exactly(t, 4, c.off)
+	assert.True(t, &buf.buf[0] == &c.buf[0])
+	assert.True(t, buf.cow)
+	assert.True(t, c.cow)
+	assert.True(t, buf.shr == c.shr)
+	assert.Exactly(t, int32(2), c.shr.refs)
+}
+
+func Test_Buffer_Clone_WriteClone(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3})
+	c := buf.Clone()
+
+	// --- When ---
+	_, err := c.WriteAt([]byte{9}, 1)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 9, 2, 3}, c.buf)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, buf.buf)
+	assert.False(t, c.cow)
+	assert.Nil(t, c.shr)
+
+	// Original is the only user of the storage now.
+	prev := &buf.buf[0]
+	_, err = buf.WriteAt([]byte{8}, 2)
+	assert.NoError(t, err)
+	assert.True(t, prev == &buf.buf[0])
+	assert.Exactly(t, []byte{0, 1, 8, 3}, buf.buf)
+	assert.Exactly(t, []byte{0, 9, 2, 3}, c.buf)
+}
+
+func Test_Buffer_Clone_WriteOriginal(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3})
+	c := buf.Clone()
+
+	// --- When ---
+	_, err := buf.Write([]byte{9})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{9, 1, 2, 3}, buf.buf)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, c.buf)
+}
+
+func Test_Buffer_Clone_Many(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3})
+	c1 := buf.Clone()
+	c2 := c1.Clone()
+
+	// --- When ---
+	require.NoError(t, c1.Close())
+
+	// --- Then ---
+	assert.Exactly(t, int32(2), buf.shr.refs)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, c2.buf)
+	require.NoError(t, c2.WriteByte(9))
+	assert.Exactly(t, []byte{9, 1, 2, 3}, c2.buf)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, buf.buf)
+}
+
+func Test_Buffer_Clone_CopyOnWrite(t *testing.T) {
+	// --- Given ---
+	data := []byte{0, 1, 2, 3}
+	buf := With(data, CopyOnWrite)
+
+	// --- When ---
+	c := buf.Clone()
+	require.NoError(t, buf.WriteByte(9))
+	require.NoError(t, c.WriteByte(8))
+
+	// --- Then ---
+	assert.Nil(t, c.shr)
+	assert.Exactly(t, []byte{9, 1, 2, 3}, buf.buf)
+	assert.Exactly(t, []byte{8, 1, 2, 3}, c.buf)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, data)
+}
+
+func Test_Buffer_Clone_Release(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3})
+	c := buf.Clone()
+
+	// --- When ---
+	got := c.Release()
+	got[0] = 9
+
+	// --- Then ---
+	assert.Exactly(t, []byte{0, 1, 2, 3}, buf.buf)
+	assert.Exactly(t, int32(1), buf.shr.refs)
+}
+
+func Test_Buffer_Clone_OffHeap(t *testing.T) {
+	// --- Given ---
+	skipNoMmap(t)
+	data := bytes.Repeat([]byte{0, 1, 2, 3}, 1000)
+	buf := New(OffHeap(1024))
+	_, err := buf.Write(data)
+	require.NoError(t, err)
+	require.True(t, buf.mapped)
+
+	// --- When ---
+	c := buf.Clone()
+	require.NoError(t, buf.Close())
+
+	// --- Then ---
+	assert.True(t, c.mapped)
+	assert.Exactly(t, data, c.buf)
+	_, err = c.Write(data)
+	assert.NoError(t, err)
+	assert.Exactly(t, bytes.Repeat(data, 2), c.buf)
+	assert.NoError(t, c.Close())
+}
+
+func Test_Buffer_Clone_Concurrent(t *testing.T) {
+	// --- Given ---
+	tpl := With(bytes.Repeat([]byte{0, 1, 2, 3}, 100))
+	clones := make([]*Buffer, 10)
+	for i := range clones {
+		clones[i] = tpl.Clone()
+	}
+
+	// --- When ---
+	done := make(chan struct{})
+	for i, c := range clones {
+		go func(i int, c *Buffer) {
+			_, _ = c.WriteAt([]byte{byte(i)}, 0)
+			done <- struct{}{}
+		}(i, c)
+	}
+	for range clones {
+		<-done
+	}
+
+	// --- Then ---
+	for i, c := range clones {
+		assert.Exactly(t, byte(i), c.buf[0])
+	}
+	assert.Exactly(t, byte(0), tpl.buf[0])
+}
@@ -0,0 +1,354 @@
+package flexbuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Downloader defaults.
+const (
+	// DefaultConcurrency is the default number of concurrent requests.
+	DefaultConcurrency = 4
+	// DefaultChunkSize is the default number of bytes requested at once.
+	DefaultChunkSize = 4 << 20
+	// DefaultRetries is the default number of retries of a failed request.
+	DefaultRetries = 3
+)
+
+// Time to wait before the first retry, it's multiplied by the attempt number.
+const retryDelay = 100 * time.Millisecond
+
+// Size of the buffer used to copy response bodies.
+const copyChunk = 32 << 10
+
+// ErrModified is returned when the resource changed during the download.
+var ErrModified = errors.New("resource modified during download")
+
+// ErrBadResponse is returned when the server response is not valid.
+var ErrBadResponse = errors.New("bad response")
+
+// Concurrency is the Downloader constructor option setting the number of
+// concurrent requests.
+func Concurrency(n int) func(*Downloader) {
+	return func(d *Downloader) {
+		d.conc = n
+	}
+}
+
+// ChunkSize is the Downloader constructor option setting the number of
+// bytes requested with single request.
+func ChunkSize(n int64) func(*Downloader) {
+	return func(d *Downloader) {
+		d.chunk = n
+	}
+}
+
+// Retries is the Downloader constructor option setting how many times
+// a failed request is retried.
+func Retries(n int) func(*Downloader) {
+	return func(d *Downloader) {
+		d.retries = n
+	}
+}
+
+// Progress is the Downloader constructor option setting the function
+// called with the number of bytes downloaded so far and the total number
+// of bytes to download (-1 if unknown). The function is called from
+// multiple goroutines.
+func Progress(fn func(done, total int64)) func(*Downloader) {
+	return func(d *Downloader) {
+		d.progress = fn
+	}
+}
+
+// Downloader downloads resources into a Buffer using concurrent HTTP range
+// requests.
+type Downloader struct {
+	// HTTP client used to make requests.
+	client *http.Client
+	// Number of concurrent requests.
+	conc int
+	// Number of bytes requested at once.
+	chunk int64
+	// Number of retries of a failed request.
+	retries int
+	// Progress function, may be nil.
+	progress func(done, total int64)
+}
+
+// NewDownloader returns new Downloader using client to make requests.
+// If client is nil http.DefaultClient is used.
+func NewDownloader(client *http.Client, opts ...func(*Downloader)) *Downloader {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	d := &Downloader{
+		client:  client,
+		conc:    DefaultConcurrency,
+		chunk:   DefaultChunkSize,
+		retries: DefaultRetries,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.conc < 1 {
+		d.conc = 1
+	}
+	if d.chunk < 1 {
+		d.chunk = DefaultChunkSize
+	}
+
+	return d
+}
+
+// Download downloads the resource at url into buf and returns its size.
+// The buffer is truncated to the size reported in the Content-Length
+// header and filled using concurrent range requests, failed requests are
+// retried. The responses are validated against the ETag and Content-Range
+// headers, ErrModified is returned if the resource changes during the
+// download, the If-Match header is sent only with strong ETags. When the
+// HEAD request fails or the server doesn't support range requests the
+// resource is downloaded with a single request.
+func (d *Downloader) Download(ctx context.Context, url string, buf *Buffer) (int64, error) {
+	size, etag, ok, err := d.probe(ctx, url)
+	if err != nil && ctx.Err() != nil {
+		return 0, err
+	}
+	if err != nil || !ok {
+		return d.single(ctx, url, buf)
+	}
+
+	if err = buf.Truncate(size); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	trk := NewTracker(buf, size)
+	chunks := make(chan Range)
+	var done int64
+	var once sync.Once
+	var wg sync.WaitGroup
+
+	for i := 0; i < d.conc; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range chunks {
+				if e := d.fetchRetry(ctx, url, etag, trk, r, &done); e != nil {
+					once.Do(func() { err = e })
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for off := int64(0); off < size; off += d.chunk {
+		r := Range{Off: off, Len: d.chunk}
+		if r.End() > size {
+			r.Len = size - off
+		}
+		select {
+		case chunks <- r:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if err != nil {
+		return 0, err
+	}
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// probe makes HEAD request and returns the resource size, ETag and true if
+// the server supports range requests.
+func (d *Downloader) probe(ctx context.Context, url string) (int64, string, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, "", false, err
+	}
+
+	rsp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", false, err
+	}
+	_ = rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return 0, "", false, fmt.Errorf("%w: status %d", ErrBadResponse, rsp.StatusCode)
+	}
+
+	ok := rsp.Header.Get("Accept-Ranges") == "bytes" && rsp.ContentLength >= 0
+	return rsp.ContentLength, rsp.Header.Get("ETag"), ok, nil
+}
+
+// fetchRetry downloads the chunk r retrying failed requests. Each retry
+// requests only the part of the chunk which was not written yet.
+func (d *Downloader) fetchRetry(ctx context.Context, url, etag string, trk *Tracker, r Range, done *int64) error {
+	var err error
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * retryDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		var n int64
+		n, err = d.fetch(ctx, url, etag, trk, r, done)
+		r.Off += n
+		r.Len -= n
+		if err == nil || errors.Is(err, ErrModified) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// fetch downloads range r and returns the number of bytes written.
+func (d *Downloader) fetch(ctx context.Context, url, etag string, trk *Tracker, r Range, done *int64) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.Off, r.End()-1))
+	if etag != "" && !strings.HasPrefix(etag, "W/") {
+		req.Header.Set("If-Match", etag) // Requires strong comparison.
+	}
+
+	rsp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+
+	switch {
+	case rsp.StatusCode == http.StatusPreconditionFailed:
+		return 0, ErrModified
+	case rsp.StatusCode != http.StatusPartialContent:
+		return 0, fmt.Errorf("%w: status %d", ErrBadResponse, rsp.StatusCode)
+	case etag != "" && rsp.Header.Get("ETag") != etag:
+		return 0, ErrModified
+	}
+
+	exp := fmt.Sprintf("bytes %d-%d/%d", r.Off, r.End()-1, trk.Size())
+	if got := rsp.Header.Get("Content-Range"); got != exp {
+		return 0, fmt.Errorf("%w: Content-Range %q", ErrBadResponse, got)
+	}
+
+	return d.copy(trk, rsp.Body, r.Off, r.Len, done)
+}
+
+// single downloads the resource with a single request.
+func (d *Downloader) single(ctx context.Context, url string, buf *Buffer) (int64, error) {
+	var err error
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * retryDelay):
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		}
+
+		var n int64
+		if n, err = d.get(ctx, url, buf); err == nil {
+			return n, nil
+		}
+		if ctx.Err() != nil {
+			return 0, err
+		}
+	}
+	return 0, err
+}
+
+// get downloads the resource with a single GET request.
+func (d *Downloader) get(ctx context.Context, url string, buf *Buffer) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	rsp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%w: status %d", ErrBadResponse, rsp.StatusCode)
+	}
+
+	if err = buf.Truncate(0); err != nil {
+		return 0, err
+	}
+
+	var done int64
+	trk := NewTracker(buf, rsp.ContentLength)
+	n, err := d.copy(trk, rsp.Body, 0, -1, &done)
+	if err != nil {
+		return n, err
+	}
+	if rsp.ContentLength >= 0 && n != rsp.ContentLength {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+// copy copies up to n bytes (or until io.EOF when n is negative) from r to
+// the tracked buffer at offset off reporting the progress. It returns the
+// number of bytes copied.
+func (d *Downloader) copy(trk *Tracker, r io.Reader, off, n int64, done *int64) (int64, error) {
+	tmp := make([]byte, copyChunk)
+
+	var total int64
+	for n < 0 || total < n {
+		p := tmp
+		if rem := n - total; n >= 0 && rem < int64(len(p)) {
+			p = p[:rem]
+		}
+
+		m, err := r.Read(p)
+		if m > 0 {
+			if _, werr := trk.WriteAt(p[:m], off+total); werr != nil {
+				return total, werr
+			}
+			total += int64(m)
+			cur := atomic.AddInt64(done, int64(m))
+			if d.progress != nil {
+				d.progress(cur, trk.Size())
+			}
+		}
+
+		if err == io.EOF {
+			if n >= 0 && total < n {
+				return total, io.ErrUnexpectedEOF
+			}
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,260 @@
+package flexbuf
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contentServer returns test server serving data with ETag and range
+// request support. The handler h, if not nil, is called before
+// serving the content and can intercept the request by returning true.
+func contentServer(t *testing.T, data []byte, h func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h != nil && h(w, r) {
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func Test_Downloader_Download(t *testing.T) {
+	// --- Given ---
+	data := testData(10000)
+	var requests int32
+	srv := contentServer(t, data, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&requests, 1)
+		}
+		return false
+	})
+
+	var mx sync.Mutex
+	var last int64
+	d := NewDownloader(
+		srv.Client(),
+		Concurrency(3),
+		ChunkSize(1000),
+		Progress(func(done, total int64) {
+			mx.Lock()
+			defer mx.Unlock()
+			assert.Exactly(t, int64(10000), total)
+			if done > last {
+				last = done
+			}
+		}),
+	)
+	buf := With([]byte{1, 2, 3})
+
+	// --- When ---
+	n, err := d.Download(context.Background(), srv.URL, buf)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(10000), n)
+	assert.Exactly(t, data, buf.buf)
+	assert.Exactly(t, int32(10), requests)
+	assert.Exactly(t, int64(10000), last)
+}
+
+func Test_Downloader_Download_Retry(t *testing.T) {
+	// --- Given ---
+	data := testData(5000)
+	var failed int32
+	srv := contentServer(t, data, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") == "bytes=2000-2999" && atomic.AddInt32(&failed, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+
+	d := NewDownloader(srv.Client(), ChunkSize(1000), Retries(1))
+	buf := &Buffer{}
+
+	// --- When ---
+	n, err := d.Download(context.Background(), srv.URL, buf)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(5000), n)
+	assert.Exactly(t, data, buf.buf)
+	assert.Exactly(t, int32(2), failed)
+}
+
+func Test_Downloader_Download_RetryExhausted(t *testing.T) {
+	// --- Given ---
+	srv := contentServer(t, testData(5000), func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusInternalServerError)
+			return true
+		}
+		return false
+	})
+
+	d := NewDownloader(srv.Client(), ChunkSize(1000), Retries(1))
+
+	// --- When ---
+	n, err := d.Download(context.Background(), srv.URL, &Buffer{})
+
+	// --- Then ---
+	assert.ErrorIs(t, err, ErrBadResponse)
+	assert.Exactly(t, int64(0), n)
+}
+
+func Test_Downloader_Download_Modified(t *testing.T) {
+	// --- Given ---
+	data := testData(5000)
+	srv := contentServer(t, data, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") == "bytes=3000-3999" {
+			w.Header().Set("ETag", `"v2"`)
+			http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(data))
+			return true
+		}
+		return false
+	})
+
+	d := NewDownloader(srv.Client(), ChunkSize(1000))
+
+	// --- When ---
+	_, err := d.Download(context.Background(), srv.URL, &Buffer{})
+
+	// --- Then ---
+	assert.ErrorIs(t, err, ErrModified)
+}
+
+func Test_Downloader_Download_BadContentRange(t *testing.T) {
+	// --- Given ---
+	srv := contentServer(t, testData(5000), func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Range") == "bytes=0-999" {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Range", "bytes 0-999/6000")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(make([]byte, 1000))
+			return true
+		}
+		return false
+	})
+
+	d := NewDownloader(srv.Client(), ChunkSize(1000), Retries(0))
+
+	// --- When ---
+	_, err := d.Download(context.Background(), srv.URL, &Buffer{})
+
+	// --- Then ---
+	assert.ErrorIs(t, err, ErrBadResponse)
+}
+
+func Test_Downloader_Download_NoRanges(t *testing.T) {
+	// --- Given ---
+	data := testData(5000)
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	var last int64
+	d := NewDownloader(srv.Client(), ChunkSize(1000), Progress(func(done, total int64) {
+		last = done
+	}))
+	buf := &Buffer{}
+
+	// --- When ---
+	n, err := d.Download(context.Background(), srv.URL, buf)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(5000), n)
+	assert.Exactly(t, data, buf.buf)
+	assert.Exactly(t, int32(2), requests)
+	assert.Exactly(t, int64(5000), last)
+}
+
+func Test_Downloader_Download_HeadNotAllowed(t *testing.T) {
+	// --- Given ---
+	data := testData(5000)
+	srv := contentServer(t, data, func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return true
+		}
+		return false
+	})
+	d := NewDownloader(srv.Client(), ChunkSize(1000))
+	buf := &Buffer{}
+
+	// --- When ---
+	n, err := d.Download(context.Background(), srv.URL, buf)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(5000), n)
+	assert.Exactly(t, data, buf.buf)
+}
+
+func Test_Downloader_Download_WeakETag(t *testing.T) {
+	// --- Given ---
+	data := testData(5000)
+	var ifMatch int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") != "" {
+			atomic.AddInt32(&ifMatch, 1)
+		}
+		w.Header().Set("ETag", `W/"v1"`)
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+	d := NewDownloader(srv.Client(), ChunkSize(1000))
+	buf := &Buffer{}
+
+	// --- When ---
+	n, err := d.Download(context.Background(), srv.URL, buf)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(5000), n)
+	assert.Exactly(t, data, buf.buf)
+	assert.Exactly(t, int32(0), ifMatch)
+}
+
+func Test_Downloader_Download_Canceled(t *testing.T) {
+	// --- Given ---
+	srv := contentServer(t, testData(5000), nil)
+	d := NewDownloader(srv.Client(), ChunkSize(1000))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// --- When ---
+	_, err := d.Download(ctx, srv.URL, &Buffer{})
+
+	// --- Then ---
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func Test_Downloader_Download_NotFound(t *testing.T) {
+	// --- Given ---
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	d := NewDownloader(srv.Client())
+
+	// --- When ---
+	_, err := d.Download(context.Background(), srv.URL, &Buffer{})
+
+	// --- Then ---
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrBadResponse)
+}
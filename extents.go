@@ -0,0 +1,167 @@
+package flexbuf
+
+import (
+	"sort"
+)
+
+// extent represents contiguous block of data at an offset.
+type extent struct {
+	// Offset of the first byte.
+	off int64
+	// Extent data.
+	data []byte
+}
+
+// end returns the offset of the first byte after the extent.
+func (e extent) end() int64 {
+	return e.off + int64(len(e.data))
+}
+
+// extents is a set of data blocks sorted by offset. The blocks never
+// overlap nor touch each other.
+type extents []extent
+
+// write writes p at offset off merging it with overlapping or adjacent
+// extents.
+func (es *extents) write(p []byte, off int64) {
+	if len(p) == 0 {
+		return
+	}
+
+	s := *es
+	end := off + int64(len(p))
+	// First extent which ends at or after p starts.
+	i := sort.Search(len(s), func(i int) bool { return s[i].end() >= off })
+	// First extent which starts after p ends.
+	j := i
+	for j < len(s) && s[j].off <= end {
+		j++
+	}
+
+	switch {
+	case i == j:
+		// No overlap, insert new extent.
+		s = append(s, extent{})
+		copy(s[i+1:], s[i:])
+		s[i] = extent{off: off, data: append([]byte(nil), p...)}
+		*es = s
+		return
+
+	case j == i+1 && s[i].off <= off && s[i].end() >= end:
+		// Overwrite inside the extent.
+		copy(s[i].data[off-s[i].off:], p)
+		return
+
+	case j == i+1 && s[i].off <= off:
+		// Extend the extent at its end.
+		s[i].data = append(s[i].data[:off-s[i].off], p...)
+		return
+	}
+
+	start := s[i].off
+	if off < start {
+		start = off
+	}
+	stop := s[j-1].end()
+	if end > stop {
+		stop = end
+	}
+
+	data := make([]byte, stop-start)
+	for _, e := range s[i:j] {
+		copy(data[e.off-start:], e.data)
+	}
+	copy(data[off-start:], p)
+
+	s[i] = extent{off: start, data: data}
+	*es = append(s[:i+1], s[j:]...)
+}
+
+// read reads len(p) bytes starting at offset off. Parts of p not covered
+// by any extent are filled by calling hole function.
+func (es extents) read(p []byte, off int64, hole func(p []byte, off int64) error) error {
+	i := sort.Search(len(es), func(i int) bool { return es[i].end() > off })
+
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+
+		if i == len(es) {
+			return hole(p[n:], pos)
+		}
+
+		e := es[i]
+		if pos < e.off {
+			// Hole before the extent.
+			m := len(p) - n
+			if gap := e.off - pos; int64(m) > gap {
+				m = int(gap)
+			}
+			if err := hole(p[n:n+m], pos); err != nil {
+				return err
+			}
+			n += m
+			continue
+		}
+
+		n += copy(p[n:], e.data[pos-e.off:])
+		i++
+	}
+
+	return nil
+}
+
+// cut removes bytes at offsets greater or equal to size.
+func (es *extents) cut(size int64) {
+	s := *es
+	i := sort.Search(len(s), func(i int) bool { return s[i].end() > size })
+	if i < len(s) && s[i].off < size {
+		s[i].data = s[i].data[:size-s[i].off]
+		i++
+	}
+	for k := i; k < len(s); k++ {
+		s[k] = extent{} // Let GC reclaim the data.
+	}
+	*es = s[:i]
+}
+
+// ranges returns byte ranges covered by the extents.
+func (es extents) ranges() []Range {
+	rs := make([]Range, len(es))
+	for i, e := range es {
+		rs[i] = Range{Off: e.off, Len: int64(len(e.data))}
+	}
+	return rs
+}
+
+// size returns the number of bytes stored in extents.
+func (es extents) size() int64 {
+	var n int64
+	for _, e := range es {
+		n += int64(len(e.data))
+	}
+	return n
+}
+
+// data returns the offset of the first byte stored in extents at or after
+// offset off. It returns false if there is no such byte.
+func (es extents) data(off int64) (int64, bool) {
+	i := sort.Search(len(es), func(i int) bool { return es[i].end() > off })
+	if i == len(es) {
+		return 0, false
+	}
+	if es[i].off > off {
+		return es[i].off, true
+	}
+	return off, true
+}
+
+// hole returns the offset of the first byte not stored in extents at or
+// after offset off.
+func (es extents) hole(off int64) int64 {
+	i := sort.Search(len(es), func(i int) bool { return es[i].end() > off })
+	if i < len(es) && es[i].off <= off {
+		return es[i].end()
+	}
+	return off
+}
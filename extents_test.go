@@ -0,0 +1,165 @@
+package flexbuf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fillHole is the hole function filling holes with 0xFF bytes.
+func fillHole(p []byte, _ int64) error {
+	for i := range p {
+		p[i] = 0xFF
+	}
+	return nil
+}
+
+func Test_extents_write(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		init extents
+		data []byte
+		off  int64
+		exp  extents
+	}{
+		{"empty", nil, []byte{1, 2}, 5, extents{{5, []byte{1, 2}}}},
+		{"nothing", extents{{5, []byte{1}}}, nil, 0, extents{{5, []byte{1}}}},
+		{"before", extents{{5, []byte{1}}}, []byte{2}, 0, extents{{0, []byte{2}}, {5, []byte{1}}}},
+		{"after", extents{{0, []byte{1}}}, []byte{2}, 5, extents{{0, []byte{1}}, {5, []byte{2}}}},
+		{"inside", extents{{0, []byte{1, 2, 3}}}, []byte{9}, 1, extents{{0, []byte{1, 9, 3}}}},
+		{"append", extents{{0, []byte{1, 2}}}, []byte{3, 4}, 2, extents{{0, []byte{1, 2, 3, 4}}}},
+		{"extend", extents{{0, []byte{1, 2}}}, []byte{3, 4}, 1, extents{{0, []byte{1, 3, 4}}}},
+		{"prepend", extents{{2, []byte{1, 2}}}, []byte{3, 4}, 0, extents{{0, []byte{3, 4, 1, 2}}}},
+		{"overlap start", extents{{2, []byte{1, 2}}}, []byte{3, 4}, 1, extents{{1, []byte{3, 4, 2}}}},
+		{
+			"join",
+			extents{{0, []byte{1, 2}}, {4, []byte{5, 6}}, {10, []byte{7}}},
+			[]byte{3, 4},
+			2,
+			extents{{0, []byte{1, 2, 3, 4, 5, 6}}, {10, []byte{7}}},
+		},
+		{
+			"cover",
+			extents{{1, []byte{1}}, {3, []byte{2}}},
+			[]byte{9, 9, 9, 9, 9},
+			0,
+			extents{{0, []byte{9, 9, 9, 9, 9}}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			es := tc.init
+
+			// --- When ---
+			es.write(tc.data, tc.off)
+
+			// --- Then ---
+			assert.Exactly(t, tc.exp, es)
+		})
+	}
+}
+
+func Test_extents_write_CopiesData(t *testing.T) {
+	// --- Given ---
+	var es extents
+	data := []byte{1, 2}
+
+	// --- When ---
+	es.write(data, 0)
+	data[0] = 9
+
+	// --- Then ---
+	assert.Exactly(t, extents{{0, []byte{1, 2}}}, es)
+}
+
+func Test_extents_read(t *testing.T) {
+	// --- Given ---
+	es := extents{{2, []byte{1, 2}}, {6, []byte{3}}}
+
+	// --- When ---
+	got := make([]byte, 9)
+	err := es.read(got, 1, fillHole)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0xFF, 1, 2, 0xFF, 0xFF, 3, 0xFF, 0xFF, 0xFF}, got)
+}
+
+func Test_extents_read_InsideExtent(t *testing.T) {
+	// --- Given ---
+	es := extents{{2, []byte{1, 2, 3, 4}}}
+
+	// --- When ---
+	got := make([]byte, 2)
+	err := es.read(got, 3, fillHole)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{2, 3}, got)
+}
+
+func Test_extents_read_HoleError(t *testing.T) {
+	// --- Given ---
+	es := extents{{2, []byte{1, 2}}}
+
+	// --- When ---
+	err := es.read(make([]byte, 4), 0, func([]byte, int64) error {
+		return errors.New("test")
+	})
+
+	// --- Then ---
+	assert.EqualError(t, err, "test")
+}
+
+func Test_extents_cut(t *testing.T) {
+	// --- Given ---
+	es := extents{{0, []byte{1, 2}}, {4, []byte{3, 4, 5}}, {10, []byte{6}}}
+
+	// --- When ---
+	es.cut(5)
+
+	// --- Then ---
+	require.Len(t, es, 2)
+	assert.Exactly(t, extents{{0, []byte{1, 2}}, {4, []byte{3}}}, es)
+	assert.Exactly(t, []Range{{0, 2}, {4, 1}}, es.ranges())
+	assert.Exactly(t, int64(3), es.size())
+}
+
+func Test_extents_data_hole(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		off     int64
+		expData int64
+		expOK   bool
+		expHole int64
+	}{
+		{"before first", 0, 2, true, 0},
+		{"first start", 2, 2, true, 4},
+		{"inside first", 3, 3, true, 4},
+		{"between", 4, 6, true, 4},
+		{"inside last", 7, 7, true, 8},
+		{"after last", 8, 0, false, 8},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			es := extents{{2, []byte{1, 2}}, {6, []byte{3, 4}}}
+
+			// --- When ---
+			data, ok := es.data(tc.off)
+			hole := es.hole(tc.off)
+
+			// --- Then ---
+			assert.Exactly(t, tc.expOK, ok)
+			assert.Exactly(t, tc.expData, data)
+			assert.Exactly(t, tc.expHole, hole)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package flexbuf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrTruncated is returned by Follower when the buffer was truncated below
+// its offset.
+var ErrTruncated = errors.New("buffer truncated")
+
+// Restart is the Follower option making it restart reading from the
+// beginning of the buffer when the buffer is truncated below its offset
+// instead of returning ErrTruncated.
+func Restart(f *Follower) {
+	f.restart = true
+}
+
+// tail synchronizes the Buffer with its followers.
+type tail struct {
+	// Guards the buffer data and the followers. Buffer methods changing
+	// the data hold the write lock, followers hold the read lock.
+	mu sync.RWMutex
+	// Active followers.
+	fs map[*Follower]struct{}
+	// Guards notify.
+	nmu sync.Mutex
+	// Closed and set to nil when the buffer changes.
+	notify chan struct{}
+}
+
+// wait returns the channel closed when the buffer changes.
+func (t *tail) wait() <-chan struct{} {
+	t.nmu.Lock()
+	defer t.nmu.Unlock()
+	if t.notify == nil {
+		t.notify = make(chan struct{})
+	}
+	return t.notify
+}
+
+// changed wakes up followers waiting for the buffer change.
+func (t *tail) changed() {
+	t.nmu.Lock()
+	defer t.nmu.Unlock()
+	if t.notify != nil {
+		close(t.notify)
+		t.notify = nil
+	}
+}
+
+// Follower reads the Buffer data like "tail -f" does. It returns the data
+// already in the buffer and then blocks waiting for more data to be
+// written. Single Follower must not be used by multiple goroutines
+// at the same time, but any number of followers may be used
+// concurrently with the buffer writer.
+type Follower struct {
+	// Offset of the next byte to read, accessed atomically. Must be
+	// the first field to be 64-bit aligned on 32-bit platforms.
+	off int64
+	// Followed buffer.
+	buf *Buffer
+	// Restart from offset zero when the buffer is truncated below off.
+	restart bool
+	// Set when the buffer was truncated below off.
+	cut bool
+	// Set when the buffer was closed or released.
+	done bool
+	// Set by Close.
+	closed bool
+}
+
+// Follow returns new Follower reading the buffer starting at offset off.
+// Once Follow is called the buffer methods changing its data synchronize
+// with followers. Follow itself must not be called concurrently with
+// other buffer methods. It panics with ErrOutOfBounds if off is negative.
+func (b *Buffer) Follow(off int64, opts ...func(*Follower)) *Follower {
+	if off < 0 {
+		panic(ErrOutOfBounds)
+	}
+
+	f := &Follower{
+		buf: b,
+		off: off,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if b.tail == nil {
+		b.tail = &tail{fs: make(map[*Follower]struct{})}
+	}
+	b.tail.mu.Lock()
+	b.tail.fs[f] = struct{}{}
+	b.tail.mu.Unlock()
+
+	return f
+}
+
+// lock locks the buffer for writing when it has followers.
+func (b *Buffer) lock() {
+	if b.tail != nil {
+		b.tail.mu.Lock()
+	}
+}
+
+// unlock unlocks the buffer locked with lock and wakes up its followers.
+func (b *Buffer) unlock() {
+	if b.tail != nil {
+		b.tail.changed()
+		b.tail.mu.Unlock()
+	}
+}
+
+// cut marks followers past the size as truncated. It must be called with
+// the buffer locked.
+func (b *Buffer) cut(size int64) {
+	if b.tail == nil {
+		return
+	}
+	for f := range b.tail.fs {
+		if atomic.LoadInt64(&f.off) > size {
+			f.cut = true
+		}
+	}
+}
+
+// end ends all followers. It must be called with the buffer locked.
+func (b *Buffer) end() {
+	if b.tail == nil {
+		return
+	}
+	for f := range b.tail.fs {
+		f.done = true
+		delete(b.tail.fs, f)
+	}
+}
+
+// Read reads up to len(p) bytes from the buffer blocking until at least
+// one byte is available. It returns io.EOF when the buffer was closed or
+// released, ErrTruncated when the buffer was truncated below the follower
+// offset (unless Restart option was used), ErrOutOfBounds when the data
+// at the follower offset was discarded and os.ErrClosed when the follower
+// was closed.
+func (f *Follower) Read(p []byte) (int, error) {
+	return f.ReadContext(context.Background(), p)
+}
+
+// ReadContext is like Read but it returns the context error when the
+// context is done before any data is available.
+func (f *Follower) ReadContext(ctx context.Context, p []byte) (int, error) {
+	t := f.buf.tail
+	for {
+		t.mu.RLock()
+		ch := t.wait()
+		n, ok, err := f.read(p)
+		t.mu.RUnlock()
+		if ok {
+			return n, err
+		}
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// read reads available data to p. It returns false when the caller
+// should wait for the buffer change. It must be called with the read
+// lock held.
+func (f *Follower) read(p []byte) (int, bool, error) {
+	switch {
+	case f.closed:
+		return 0, true, os.ErrClosed
+	case f.done:
+		return 0, true, io.EOF
+	case f.cut && !f.restart:
+		return 0, true, ErrTruncated
+	case f.cut:
+		f.cut = false
+		atomic.StoreInt64(&f.off, f.buf.base+int64(f.buf.low))
+	}
+
+	if len(p) == 0 {
+		return 0, true, nil
+	}
+	i, err := f.buf.rel(atomic.LoadInt64(&f.off))
+	if err != nil {
+		return 0, true, err
+	}
+	if buf := f.buf.buf; i < len(buf) {
+		n := copy(p, buf[i:])
+		atomic.AddInt64(&f.off, int64(n))
+		return n, true, nil
+	}
+	return 0, false, nil
+}
+
+// Offset returns the offset of the next byte to read. It may be called
+// concurrently with Read.
+func (f *Follower) Offset() int64 {
+	return atomic.LoadInt64(&f.off)
+}
+
+// Close stops following the buffer. The pending and future reads return
+// os.ErrClosed. It always returns nil error.
+func (f *Follower) Close() error {
+	t := f.buf.tail
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f.closed = true
+	delete(t.fs, f)
+	t.changed()
+	return nil
+}
@@ -0,0 +1,182 @@
+package flexbuf
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Buffer_Follow(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2})
+
+	// --- When ---
+	f := buf.Follow(1)
+
+	// --- Then ---
+	got := make([]byte, 5)
+	n, err := f.Read(got)
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, []byte{1, 2}, got[:n])
+	assert.Exactly(t, int64(3), f.Offset())
+}
+
+func Test_Buffer_Follow_Panics(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+
+	// --- Then ---
+	assert.PanicsWithValue(t, ErrOutOfBounds, func() { buf.Follow(-1) })
+}
+
+func Test_Buffer_Follow_Blocks(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	data := testData(10000)
+
+	var wg sync.WaitGroup
+	got := make([][]byte, 4)
+	fs := make([]*Follower, len(got))
+	for i := range got {
+		f := buf.Follow(0)
+		fs[i] = f
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], _ = ioutil.ReadAll(f)
+		}(i)
+	}
+
+	// --- When ---
+	for i := 0; i < len(data); i += 100 {
+		switch i % 300 {
+		case 0:
+			_, _ = buf.Write(data[i : i+100])
+		case 100:
+			_, _ = buf.WriteAt(data[i:i+100], int64(i))
+			_, _ = buf.Seek(0, io.SeekEnd)
+		default:
+			_, _ = buf.ReadFrom(strings.NewReader(string(data[i : i+100])))
+		}
+	}
+	// Close drops the data so wait for followers to read it.
+	for _, f := range fs {
+		for f.Offset() < int64(len(data)) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	_ = buf.Close()
+	wg.Wait()
+
+	// --- Then ---
+	for i := range got {
+		assert.Exactly(t, data, got[i])
+	}
+}
+
+func Test_Buffer_Follow_Truncate(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3})
+	f := buf.Follow(3)
+
+	// --- When ---
+	require.NoError(t, buf.Truncate(2))
+
+	// --- Then ---
+	n, err := f.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrTruncated)
+	assert.Exactly(t, 0, n)
+
+	// The error is sticky.
+	_, _ = buf.Write([]byte{4, 5, 6})
+	_, err = f.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+func Test_Buffer_Follow_Truncate_Restart(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3})
+	f := buf.Follow(3, Restart)
+
+	// --- When ---
+	require.NoError(t, buf.Truncate(2))
+
+	// --- Then ---
+	got := make([]byte, 4)
+	n, err := f.Read(got)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1}, got[:n])
+}
+
+func Test_Buffer_Follow_Truncate_AtOffset(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3})
+	f := buf.Follow(2)
+
+	// --- When ---
+	require.NoError(t, buf.Truncate(2))
+	_, _ = buf.WriteAt([]byte{9}, 2)
+
+	// --- Then ---
+	got := make([]byte, 4)
+	n, err := f.Read(got)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{9}, got[:n])
+}
+
+func Test_Buffer_Follow_ReadContext(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1})
+	f := buf.Follow(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// --- When ---
+	n, err := f.ReadContext(ctx, make([]byte, 1))
+
+	// --- Then ---
+	assert.Exactly(t, context.DeadlineExceeded, err)
+	assert.Exactly(t, 0, n)
+}
+
+func Test_Follower_Close(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	f := buf.Follow(0)
+	done := make(chan error)
+	go func() {
+		_, err := f.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	// --- When ---
+	require.NoError(t, f.Close())
+
+	// --- Then ---
+	assert.ErrorIs(t, <-done, os.ErrClosed)
+	assert.Len(t, buf.tail.fs, 0)
+}
+
+func Test_Buffer_Follow_Release(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1})
+	f := buf.Follow(2)
+
+	// --- When ---
+	got := buf.Release()
+
+	// --- Then ---
+	assert.Exactly(t, []byte{0, 1}, got)
+	n, err := f.Read(make([]byte, 1))
+	assert.Exactly(t, io.EOF, err)
+	assert.Exactly(t, 0, n)
+}
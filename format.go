@@ -0,0 +1,83 @@
+package flexbuf
+
+import (
+	"encoding/hex"
+	"strconv"
+)
+
+// The methods below format values as the strconv Append functions do,
+// writing directly to the buffer at the current offset without
+// allocations. Like Write they overwrite the existing bytes, grow the
+// buffer as needed, advance the offset and return the number of bytes
+// written. The error is always nil.
+
+// WriteInt writes the string form of v in given base.
+func (b *Buffer) WriteInt(v int64, base int) (int, error) {
+	// The base 2 with the sign is the longest.
+	return b.format(65, func(p []byte) []byte {
+		return strconv.AppendInt(p, v, base)
+	}), nil
+}
+
+// WriteUint writes the string form of v in given base.
+func (b *Buffer) WriteUint(v uint64, base int) (int, error) {
+	return b.format(64, func(p []byte) []byte {
+		return strconv.AppendUint(p, v, base)
+	}), nil
+}
+
+// WriteFloat writes the string form of f as generated by
+// strconv.FormatFloat.
+func (b *Buffer) WriteFloat(f float64, fmt byte, prec, bitSize int) (int, error) {
+	// The longest are the smallest denormals in 'f' format: sign, "0.",
+	// 323 zeros and up to 17 significant digits, or prec digits.
+	max := 350
+	if prec > 0 {
+		max += prec
+	}
+	return b.format(max, func(p []byte) []byte {
+		return strconv.AppendFloat(p, f, fmt, prec, bitSize)
+	}), nil
+}
+
+// WriteBool writes "true" or "false" according to v.
+func (b *Buffer) WriteBool(v bool) (int, error) {
+	return b.format(5, func(p []byte) []byte {
+		return strconv.AppendBool(p, v)
+	}), nil
+}
+
+// WriteQuote writes a double-quoted Go string literal representing s as
+// generated by strconv.Quote.
+func (b *Buffer) WriteQuote(s string) (int, error) {
+	// Every byte is at most escaped as \xhh.
+	return b.format(4*len(s)+2, func(p []byte) []byte {
+		return strconv.AppendQuote(p, s)
+	}), nil
+}
+
+// WriteHex writes the lower case hexadecimal encoding of p.
+func (b *Buffer) WriteHex(p []byte) (int, error) {
+	b.lock()
+	defer b.unlock()
+	return hex.Encode(b.slot(2*len(p)), p), nil
+}
+
+// format writes the bytes appended by fn to an empty slice at the current
+// offset and advances the offset. The max is the upper bound of the
+// number of bytes fn appends so they are appended in place.
+func (b *Buffer) format(max int, fn func([]byte) []byte) int {
+	b.lock()
+	defer b.unlock()
+
+	b.own()
+	l := len(b.buf)
+	b.grow(max)
+	n := len(fn(b.buf[b.off:b.off]))
+	b.off += n
+	if b.off > l {
+		l = b.off
+	}
+	b.buf = b.buf[:l]
+	return n
+}
@@ -0,0 +1,102 @@
+package flexbuf
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Buffer_Format(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		fn  func(b *Buffer) (int, error)
+		exp string
+	}{
+		{"int", func(b *Buffer) (int, error) { return b.WriteInt(-123, 10) }, "-123"},
+		{"int min base 2", func(b *Buffer) (int, error) { return b.WriteInt(math.MinInt64, 2) }, strconv.FormatInt(math.MinInt64, 2)},
+		{"uint", func(b *Buffer) (int, error) { return b.WriteUint(255, 16) }, "ff"},
+		{"uint max base 2", func(b *Buffer) (int, error) { return b.WriteUint(math.MaxUint64, 2) }, strconv.FormatUint(math.MaxUint64, 2)},
+		{"float", func(b *Buffer) (int, error) { return b.WriteFloat(1.5, 'f', 2, 64) }, "1.50"},
+		{"float shortest", func(b *Buffer) (int, error) { return b.WriteFloat(0.1, 'g', -1, 32) }, "0.1"},
+		{"float denormal", func(b *Buffer) (int, error) {
+			return b.WriteFloat(-math.SmallestNonzeroFloat64, 'f', -1, 64)
+		}, strconv.FormatFloat(-math.SmallestNonzeroFloat64, 'f', -1, 64)},
+		{"float max", func(b *Buffer) (int, error) { return b.WriteFloat(-math.MaxFloat64, 'f', 30, 64) }, strconv.FormatFloat(-math.MaxFloat64, 'f', 30, 64)},
+		{"bool true", func(b *Buffer) (int, error) { return b.WriteBool(true) }, "true"},
+		{"bool false", func(b *Buffer) (int, error) { return b.WriteBool(false) }, "false"},
+		{"quote", func(b *Buffer) (int, error) { return b.WriteQuote("a\"b\n") }, `"a\"b\n"`},
+		{"quote invalid", func(b *Buffer) (int, error) { return b.WriteQuote("\x01\xff") }, `"\x01\xff"`},
+		{"hex", func(b *Buffer) (int, error) { return b.WriteHex([]byte{0x01, 0xab}) }, "01ab"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := &Buffer{}
+
+			// --- When ---
+			n, err := tc.fn(buf)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, len(tc.exp), n)
+			assert.Exactly(t, tc.exp, string(buf.buf))
+			assert.Exactly(t, len(tc.exp), buf.Offset())
+			assert.Exactly(t, len(tc.exp), buf.Len())
+		})
+	}
+}
+
+func Test_Buffer_Format_Overwrite(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte("abcdefgh"), Offset(2))
+
+	// --- When ---
+	_, _ = buf.WriteInt(12, 10)
+	_, _ = buf.WriteBool(true)
+
+	// --- Then ---
+	assert.Exactly(t, "ab12true", string(buf.buf))
+	assert.Exactly(t, 8, buf.Offset())
+
+	_, _ = buf.WriteHex([]byte{0xff, 0xee})
+	assert.Exactly(t, "ab12trueffee", string(buf.buf))
+	assert.Exactly(t, 12, buf.Len())
+	assert.Exactly(t, 12, buf.Offset())
+}
+
+func Test_Buffer_Format_KeepsZeroedCapacity(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+
+	// --- When ---
+	_, _ = buf.WriteFloat(1, 'f', 0, 64)
+	_ = buf.Truncate(8)
+
+	// --- Then ---
+	assert.Exactly(t, []byte{'1', 0, 0, 0, 0, 0, 0, 0}, buf.buf)
+}
+
+func Test_Buffer_Format_NoAllocs(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	buf.Grow(1 << 12)
+	data := []byte{1, 2, 3}
+
+	// --- When ---
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.SeekStart()
+		_, _ = buf.WriteInt(-1234567, 10)
+		_, _ = buf.WriteUint(math.MaxUint64, 16)
+		_, _ = buf.WriteFloat(math.Pi, 'g', -1, 64)
+		_, _ = buf.WriteBool(true)
+		_, _ = buf.WriteQuote("hello\tworld")
+		_, _ = buf.WriteHex(data)
+	})
+
+	// --- Then ---
+	assert.Exactly(t, float64(0), allocs)
+}
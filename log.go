@@ -0,0 +1,212 @@
+package flexbuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// ErrCorrupt is returned when the log record checksum doesn't match.
+var ErrCorrupt = errors.New("corrupt log record")
+
+// logHeader is the size of the log record header.
+const logHeader = 8
+
+// castagnoli is the CRC32C table used by Log.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// LogStore is the interface of the storage used by Log. It's implemented
+// by Buffer, Paged (use NewCache for file backed storage), Sparse and
+// Overlay.
+type LogStore interface {
+	ReaderWriterAt
+	// Truncate changes the size of the storage.
+	Truncate(size int64) error
+	// Size returns the size of the storage.
+	Size() int64
+}
+
+// Log is an append-only log of variable length records. Each record is
+// stored at its offset as a little endian uint32 payload length, little
+// endian uint32 CRC32C (Castagnoli) checksum of the length and the payload
+// and the payload itself. Because the checksum covers the length zero
+// filled storage is never taken for valid empty records. Log is not safe
+// for concurrent use.
+type Log struct {
+	// Storage.
+	s LogStore
+	// Offset of the next record.
+	end int64
+	// Header of written records.
+	hdr [logHeader]byte
+}
+
+// NewLog returns new Log appending records at the end of s. The
+// existing records are not validated, use Recover to remove the record
+// which was not completely written.
+func NewLog(s LogStore) *Log {
+	return &Log{
+		s:   s,
+		end: s.Size(),
+	}
+}
+
+// Append appends record p to the log. It returns the record offset and
+// ErrTooLong when p is longer than math.MaxUint32 bytes.
+func (l *Log) Append(p []byte) (int64, error) {
+	if int64(len(p)) > math.MaxUint32 {
+		return 0, ErrTooLong
+	}
+
+	off := l.end
+	binary.LittleEndian.PutUint32(l.hdr[:], uint32(len(p)))
+	binary.LittleEndian.PutUint32(l.hdr[4:], logSum(l.hdr[:4], p))
+	if _, err := l.s.WriteAt(l.hdr[:], off); err != nil {
+		return 0, err
+	}
+	if _, err := l.s.WriteAt(p, off+logHeader); err != nil {
+		return 0, err
+	}
+	l.end += logHeader + int64(len(p))
+	return off, nil
+}
+
+// ReadAt reads the record at offset off. It returns the record and the
+// offset of the next record. It returns io.EOF when off is the end of the
+// log, io.ErrUnexpectedEOF when the record is not complete and ErrCorrupt
+// when its checksum doesn't match.
+func (l *Log) ReadAt(off int64) ([]byte, int64, error) {
+	return l.read(nil, off)
+}
+
+// read reads the record at offset off to p, p is reallocated when it is
+// too small. See ReadAt.
+func (l *Log) read(p []byte, off int64) ([]byte, int64, error) {
+	switch {
+	case off < 0:
+		return nil, 0, ErrOutOfBounds
+	case off >= l.end:
+		return nil, 0, io.EOF
+	case off+logHeader > l.end:
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	var hdr [logHeader]byte
+	if _, err := l.s.ReadAt(hdr[:], off); err != nil {
+		return nil, 0, err
+	}
+	n := int64(binary.LittleEndian.Uint32(hdr[:]))
+	sum := binary.LittleEndian.Uint32(hdr[4:])
+	next := off + logHeader + n
+	if next > l.end {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	if int64(cap(p)) < n {
+		p = make([]byte, n)
+	}
+	p = p[:n]
+	if n > 0 {
+		if _, err := l.s.ReadAt(p, off+logHeader); err != nil {
+			return nil, 0, err
+		}
+	}
+	if logSum(hdr[:4], p) != sum {
+		return nil, 0, ErrCorrupt
+	}
+	return p, next, nil
+}
+
+// logSum returns the checksum of the record with length field n and
+// payload p.
+func logSum(n, p []byte) uint32 {
+	return crc32.Update(crc32.Checksum(n, castagnoli), castagnoli, p)
+}
+
+// Scan returns scanner iterating over records starting at offset off.
+func (l *Log) Scan(off int64) *LogScanner {
+	return &LogScanner{l: l, next: off}
+}
+
+// Recover validates all the records and truncates the log at the first
+// record which is not complete or whose checksum doesn't match, as left
+// by the write interrupted by a crash. It returns the number of removed
+// bytes.
+func (l *Log) Recover() (int64, error) {
+	l.end = l.s.Size()
+	sc := l.Scan(0)
+	for sc.Next() {
+	}
+
+	switch sc.Err() {
+	case nil:
+		return 0, nil
+	case io.ErrUnexpectedEOF, ErrCorrupt:
+	default:
+		return 0, sc.Err()
+	}
+
+	n := l.end - sc.next
+	if err := l.s.Truncate(sc.next); err != nil {
+		return 0, err
+	}
+	l.end = sc.next
+	return n, nil
+}
+
+// Size returns the size of the log in bytes, that is the offset of the
+// next appended record.
+func (l *Log) Size() int64 {
+	return l.end
+}
+
+// LogScanner iterates over Log records.
+type LogScanner struct {
+	l *Log
+	// Offset of the current record.
+	off int64
+	// Offset of the next record.
+	next int64
+	// Current record.
+	rec []byte
+	// Scan error.
+	err error
+}
+
+// Next advances the scanner to the next record. It returns false when
+// there are no more records or an error occurred.
+func (sc *LogScanner) Next() bool {
+	if sc.err != nil {
+		return false
+	}
+
+	rec, next, err := sc.l.read(sc.rec, sc.next)
+	if err != nil {
+		if err != io.EOF {
+			sc.err = err
+		}
+		return false
+	}
+	sc.rec = rec
+	sc.off = sc.next
+	sc.next = next
+	return true
+}
+
+// Record returns the current record. The returned slice is reused by
+// the next call to Next.
+func (sc *LogScanner) Record() []byte {
+	return sc.rec
+}
+
+// Offset returns the offset of the current record.
+func (sc *LogScanner) Offset() int64 {
+	return sc.off
+}
+
+// Err returns the error which stopped the scan.
+func (sc *LogScanner) Err() error {
+	return sc.err
+}
@@ -0,0 +1,180 @@
+package flexbuf
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Log_Append(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	l := NewLog(buf)
+
+	// --- When ---
+	off1, err1 := l.Append([]byte("abc"))
+	off2, err2 := l.Append(nil)
+
+	// --- Then ---
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Exactly(t, int64(0), off1)
+	assert.Exactly(t, int64(11), off2)
+	assert.Exactly(t, int64(19), l.Size())
+	exp := []byte{
+		3, 0, 0, 0, 0xf8, 0x83, 0x14, 0x55, 'a', 'b', 'c',
+		0, 0, 0, 0, 0xc7, 0x4b, 0x67, 0x48,
+	}
+	assert.Exactly(t, exp, buf.buf)
+}
+
+func Test_Log_ReadAt(t *testing.T) {
+	// --- Given ---
+	l := NewLog(&Buffer{})
+	_, _ = l.Append([]byte("abc"))
+	off, _ := l.Append([]byte("de"))
+
+	// --- When ---
+	rec, next, err := l.ReadAt(off)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte("de"), rec)
+	assert.Exactly(t, l.Size(), next)
+
+	_, _, err = l.ReadAt(next)
+	assert.Exactly(t, io.EOF, err)
+	_, _, err = l.ReadAt(-1)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	_, _, err = l.ReadAt(1)
+	assert.Exactly(t, io.ErrUnexpectedEOF, err)
+}
+
+func Test_Log_Scan(t *testing.T) {
+	// --- Given ---
+	l := NewLog(&Buffer{})
+	recs := []string{"a", "", "bcd", "efghijkl"}
+	var offs []int64
+	for _, r := range recs {
+		off, err := l.Append([]byte(r))
+		require.NoError(t, err)
+		offs = append(offs, off)
+	}
+
+	// --- When ---
+	var got []string
+	var gotOffs []int64
+	sc := l.Scan(0)
+	for sc.Next() {
+		got = append(got, string(sc.Record()))
+		gotOffs = append(gotOffs, sc.Offset())
+	}
+
+	// --- Then ---
+	assert.NoError(t, sc.Err())
+	assert.Exactly(t, recs, got)
+	assert.Exactly(t, offs, gotOffs)
+}
+
+func Test_Log_Recover(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		tear func(buf *Buffer)
+		exp  int64
+	}{
+		{"intact", func(buf *Buffer) {}, 0},
+		{"zero filled tail", func(buf *Buffer) { _ = buf.Truncate(buf.Size() + 16) }, 16},
+		{"zero filled record", func(buf *Buffer) { zeroOutSlice(buf.buf[23:]) }, 12},
+		{"torn header", func(buf *Buffer) { _ = buf.Truncate(27) }, 4},
+		{"torn payload", func(buf *Buffer) { _ = buf.Truncate(30) }, 7},
+		{"bad checksum", func(buf *Buffer) { buf.buf[31] ^= 1 }, 12},
+		{"bad checksum in the middle", func(buf *Buffer) { buf.buf[19] ^= 1 }, 24},
+		{"garbage length", func(buf *Buffer) { _, _ = buf.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 23) }, 12},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := &Buffer{}
+			l := NewLog(buf)
+			_, _ = l.Append([]byte("abc"))
+			_, _ = l.Append([]byte("defg"))
+			_, _ = l.Append([]byte("hijk"))
+			tc.tear(buf)
+			size := buf.Size()
+
+			// --- When ---
+			l = NewLog(buf)
+			n, err := l.Recover()
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.exp, n)
+			assert.Exactly(t, size-n, buf.Size())
+			assert.Exactly(t, buf.Size(), l.Size())
+
+			sc := l.Scan(0)
+			for sc.Next() {
+			}
+			assert.NoError(t, sc.Err())
+
+			off, err := l.Append([]byte("z"))
+			assert.NoError(t, err)
+			rec, _, err := l.ReadAt(off)
+			assert.NoError(t, err)
+			assert.Exactly(t, []byte("z"), rec)
+		})
+	}
+}
+
+func Test_Log_File(t *testing.T) {
+	// --- Given ---
+	f := TempFile(t, os.O_RDWR, nil)
+	pg := NewCache(f, 0)
+	l := NewLog(pg)
+	_, err := l.Append([]byte("abc"))
+	require.NoError(t, err)
+	_, err = l.Append([]byte("defg"))
+	require.NoError(t, err)
+	require.NoError(t, pg.Flush())
+	require.NoError(t, f.Truncate(15))
+
+	// --- When ---
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	pg = NewCache(f, fi.Size())
+	l = NewLog(pg)
+	n, err := l.Recover()
+
+	// --- Then ---
+	require.NoError(t, err)
+	assert.Exactly(t, int64(4), n)
+	require.NoError(t, pg.Flush())
+	fi, err = os.Stat(f.Name())
+	require.NoError(t, err)
+	assert.Exactly(t, int64(11), fi.Size())
+}
+
+func Test_Log_Scan_ZeroFilledTail(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	l := NewLog(buf)
+	_, _ = l.Append([]byte("abc"))
+	require.NoError(t, buf.Truncate(buf.Size()+16))
+	l = NewLog(buf)
+
+	// --- When ---
+	var cnt int
+	sc := l.Scan(0)
+	for sc.Next() {
+		cnt++
+	}
+
+	// --- Then ---
+	assert.Exactly(t, 1, cnt)
+	assert.Exactly(t, ErrCorrupt, sc.Err())
+}
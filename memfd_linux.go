@@ -0,0 +1,254 @@
+//go:build linux && (amd64 || arm64 || loong64 || ppc64 || ppc64le || riscv64)
+// +build linux
+// +build amd64 arm64 loong64 ppc64 ppc64le riscv64
+
+package flexbuf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Memory file seals, see fcntl(2) for details.
+const (
+	// SealSeal prevents further seals from being set.
+	SealSeal = 0x1
+	// SealShrink prevents the file from shrinking.
+	SealShrink = 0x2
+	// SealGrow prevents the file from growing.
+	SealGrow = 0x4
+	// SealWrite prevents writes to the file.
+	SealWrite = 0x8
+	// SealFutureWrite prevents writes to the file using new descriptors
+	// and mappings.
+	SealFutureWrite = 0x10
+)
+
+// memfd_create(2) and fcntl(2) constants not present in syscall package.
+const (
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+	fAddSeals       = 1033
+	fGetSeals       = 1034
+)
+
+// Memfd is a Buffer backed by an anonymous memory file created with
+// memfd_create(2). The buffer data lives in the file's memory mapping so
+// it can be shared with other processes, for example using
+// exec.Cmd.ExtraFiles or the path returned by the Path method, without
+// copying it through pipes.
+type Memfd struct {
+	*Buffer
+	fil *os.File
+	mem *memfdAlloc
+}
+
+// NewMemfd returns new Buffer backed by the memory file. The name is used
+// for debugging purposes only, it's visible as the target of the symlink
+// in /proc/self/fd directory.
+func NewMemfd(name string) (*Memfd, error) {
+	fd, err := memfdCreate(name, mfdCloexec|mfdAllowSealing)
+	if err != nil {
+		return nil, err
+	}
+
+	mem := &memfdAlloc{fd: int(fd)}
+	m := &Memfd{
+		Buffer: &Buffer{mem: mem},
+		fil:    os.NewFile(fd, "memfd:"+name),
+		mem:    mem,
+	}
+	return m, nil
+}
+
+// memfdCreate creates the memory file with given name and flags.
+func memfdCreate(name string, flags uintptr) (uintptr, error) {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+
+	fd, _, errno := syscall.Syscall(
+		sysMemfdCreate,
+		uintptr(unsafe.Pointer(p)),
+		flags,
+		0,
+	)
+	if errno != 0 {
+		return 0, os.NewSyscallError("memfd_create", errno)
+	}
+	return fd, nil
+}
+
+// File sets the memory file size to the buffer length and returns the file
+// with its offset set to zero. The file is owned by Memfd and is closed by
+// the Close method. Writes to the buffer are visible through the file
+// until it is sealed, but File must be called again to update the file
+// size after the buffer length changes.
+func (m *Memfd) File() (*os.File, error) {
+	if err := m.sync(); err != nil {
+		return nil, err
+	}
+	if _, err := m.fil.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return m.fil, nil
+}
+
+// Path returns the path other processes can use to open the memory file.
+// Call File first to make sure the file size matches the buffer length.
+func (m *Memfd) Path() string {
+	return fmt.Sprintf("/proc/%d/fd/%d", os.Getpid(), m.mem.fd)
+}
+
+// Seal sets the memory file size to the buffer length and adds seals to
+// the file. Seals other than SealSeal freeze the file - the buffer data is
+// moved to the Go heap and changes made to the buffer after the call are
+// not visible through the file. When adding seals fails the buffer is
+// left mapped to the file.
+func (m *Memfd) Seal(seals int) error {
+	if err := m.sync(); err != nil {
+		return err
+	}
+
+	// The writable mapping must be removed before adding SealWrite.
+	freeze := seals&^SealSeal != 0 && !m.mem.sealed
+	mapped := m.mapped
+	if freeze {
+		m.mem.sealed = true
+		if mapped {
+			m.move() // Sealed allocator moves data to the Go heap.
+		}
+	}
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_FCNTL,
+		uintptr(m.mem.fd),
+		fAddSeals,
+		uintptr(seals),
+	)
+	if errno != 0 {
+		if freeze {
+			m.mem.sealed = false
+			if mapped {
+				m.move() // Map the file again.
+			}
+		}
+		return os.NewSyscallError("fcntl", errno)
+	}
+	return nil
+}
+
+// move reallocates the buffer data keeping its length.
+func (m *Memfd) move() {
+	l := len(m.buf)
+	m.realloc(l)
+	m.buf = m.buf[:l]
+}
+
+// Seals returns seals set on the memory file.
+func (m *Memfd) Seals() (int, error) {
+	seals, _, errno := syscall.Syscall(
+		syscall.SYS_FCNTL,
+		uintptr(m.mem.fd),
+		fGetSeals,
+		0,
+	)
+	if errno != 0 {
+		return 0, os.NewSyscallError("fcntl", errno)
+	}
+	return int(seals), nil
+}
+
+// Close releases the buffer memory and closes the memory file.
+func (m *Memfd) Close() error {
+	_ = m.Buffer.Close()
+	m.mem.fd = -1
+	return m.fil.Close()
+}
+
+// sync sets the memory file size and its mapping size to buffer length.
+func (m *Memfd) sync() error {
+	if !m.mapped {
+		return nil
+	}
+
+	l := len(m.buf)
+	c := cap(m.buf)
+	if l == c {
+		return nil
+	}
+
+	if err := m.mem.truncate(l); err != nil {
+		return err
+	}
+
+	if l == 0 {
+		m.drop()
+		m.buf = nil
+		return nil
+	}
+
+	tmp, err := mremap(m.buf[:c], l)
+	if err != nil {
+		// Restore the size so all mapped bytes are backed by the file.
+		_ = m.mem.truncate(c)
+		return os.NewSyscallError("mremap", err)
+	}
+	m.buf = tmp
+	return nil
+}
+
+// memfdAlloc allocates memory by mapping the memory file.
+type memfdAlloc struct {
+	// Memory file descriptor, -1 after the file is closed.
+	fd int
+	// True when the file was sealed.
+	sealed bool
+}
+
+func (a *memfdAlloc) realloc(p []byte, mapped bool, n int) ([]byte, bool) {
+	if a.fd < 0 || a.sealed {
+		return nil, false
+	}
+
+	if err := a.truncate(n); err != nil {
+		return nil, false
+	}
+
+	if mapped {
+		tmp, err := mremap(p[:cap(p)], n)
+		if err != nil {
+			return nil, false
+		}
+		return tmp, true
+	}
+
+	tmp, err := mmapFd(n, a.fd, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, false
+	}
+	copy(tmp, p)
+	return tmp, true
+}
+
+func (a *memfdAlloc) free(p []byte) {
+	_ = munmap(p[:cap(p)])
+}
+
+// shareable returns false because the memory mapping is shared with
+// the memory file.
+func (a *memfdAlloc) shareable() bool {
+	return false
+}
+
+// truncate changes the memory file size.
+func (a *memfdAlloc) truncate(size int) error {
+	if err := syscall.Ftruncate(a.fd, int64(size)); err != nil {
+		return os.NewSyscallError("ftruncate", err)
+	}
+	return nil
+}
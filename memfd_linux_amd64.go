@@ -0,0 +1,4 @@
+package flexbuf
+
+// sysMemfdCreate is the memfd_create system call number.
+const sysMemfdCreate = 319
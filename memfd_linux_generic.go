@@ -0,0 +1,12 @@
+//go:build linux && (arm64 || loong64 || riscv64)
+// +build linux
+// +build arm64 loong64 riscv64
+
+package flexbuf
+
+import (
+	"syscall"
+)
+
+// sysMemfdCreate is the memfd_create system call number.
+const sysMemfdCreate = syscall.SYS_MEMFD_CREATE
@@ -0,0 +1,8 @@
+//go:build linux && (ppc64 || ppc64le)
+// +build linux
+// +build ppc64 ppc64le
+
+package flexbuf
+
+// sysMemfdCreate is the memfd_create system call number.
+const sysMemfdCreate = 360
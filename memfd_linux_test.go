@@ -0,0 +1,186 @@
+//go:build linux && (amd64 || arm64 || loong64 || ppc64 || ppc64le || riscv64)
+// +build linux
+// +build amd64 arm64 loong64 ppc64 ppc64le riscv64
+
+package flexbuf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Memfd_File(t *testing.T) {
+	// --- Given ---
+	m, err := NewMemfd("test")
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.Write([]byte{0, 1, 2, 3})
+	require.NoError(t, err)
+
+	// --- When ---
+	fil, err := m.File()
+
+	// --- Then ---
+	require.NoError(t, err)
+	assert.True(t, m.mapped)
+	got, err := ioutil.ReadAll(fil)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, got)
+}
+
+func Test_Memfd_File_Grow(t *testing.T) {
+	// --- Given ---
+	m, err := NewMemfd("test")
+	require.NoError(t, err)
+	defer m.Close()
+
+	data := bytes.Repeat([]byte{0, 1, 2, 3}, 1000)
+	_, err = m.Write(data)
+	require.NoError(t, err)
+	_, err = m.File()
+	require.NoError(t, err)
+
+	// --- When ---
+	_, err = m.Write(data)
+	require.NoError(t, err)
+	fil, err := m.File()
+	require.NoError(t, err)
+
+	// --- Then ---
+	assert.Exactly(t, 8000, m.Cap())
+	got, err := ioutil.ReadAll(fil)
+	assert.NoError(t, err)
+	assert.Exactly(t, bytes.Repeat(data, 2), got)
+}
+
+func Test_Memfd_Path(t *testing.T) {
+	// --- Given ---
+	m, err := NewMemfd("test")
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.WriteString("abc")
+	require.NoError(t, err)
+	_, err = m.File()
+	require.NoError(t, err)
+
+	// --- When ---
+	got, err := ioutil.ReadFile(m.Path())
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte("abc"), got)
+}
+
+func Test_Memfd_ExtraFiles(t *testing.T) {
+	// --- Given ---
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	m, err := NewMemfd("test")
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.WriteString("abc")
+	require.NoError(t, err)
+	fil, err := m.File()
+	require.NoError(t, err)
+
+	cmd := exec.Command("sh", "-c", "cat <&3")
+	cmd.ExtraFiles = []*os.File{fil}
+
+	// --- When ---
+	got, err := cmd.Output()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte("abc"), got)
+}
+
+func Test_Memfd_Seal(t *testing.T) {
+	// --- Given ---
+	m, err := NewMemfd("test")
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.WriteString("abc")
+	require.NoError(t, err)
+
+	// --- When ---
+	err = m.Seal(SealShrink | SealGrow | SealWrite)
+
+	// --- Then ---
+	require.NoError(t, err)
+	assert.False(t, m.mapped)
+
+	seals, err := m.Seals()
+	assert.NoError(t, err)
+	assert.Exactly(t, SealShrink|SealGrow|SealWrite, seals)
+
+	_, err = m.WriteString("def")
+	assert.NoError(t, err)
+	assert.Exactly(t, "abcdef", string(m.buf))
+
+	fil, err := m.File()
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(fil)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte("abc"), got)
+}
+
+func Test_Memfd_Seal_Error(t *testing.T) {
+	// --- Given ---
+	m, err := NewMemfd("test")
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.WriteString("abc")
+	require.NoError(t, err)
+	require.NoError(t, m.Seal(SealSeal))
+
+	// --- When ---
+	err = m.Seal(SealWrite)
+
+	// --- Then ---
+	require.Error(t, err)
+	assert.True(t, m.mapped)
+	assert.False(t, m.mem.sealed)
+
+	_, err = m.WriteString("def")
+	assert.NoError(t, err)
+
+	fil, err := m.File()
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(fil)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte("abcdef"), got)
+}
+
+func Test_Memfd_Truncate(t *testing.T) {
+	// --- Given ---
+	m, err := NewMemfd("test")
+	require.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.WriteString("abcdef")
+	require.NoError(t, err)
+
+	// --- When ---
+	require.NoError(t, m.Truncate(0))
+	fil, err := m.File()
+
+	// --- Then ---
+	require.NoError(t, err)
+	assert.False(t, m.mapped)
+	got, err := ioutil.ReadAll(fil)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{}, got)
+}
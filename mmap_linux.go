@@ -0,0 +1,82 @@
+//go:build linux && (amd64 || arm64 || loong64 || ppc64 || ppc64le || riscv64)
+// +build linux
+// +build amd64 arm64 loong64 ppc64 ppc64le riscv64
+
+package flexbuf
+
+import (
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+// mremapMayMove allows kernel to relocate the mapping.
+const mremapMayMove = 0x1
+
+// mmap creates new anonymous private memory mapping of length n.
+func mmap(n int) ([]byte, error) {
+	return mmapFd(n, -1, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+}
+
+// mmapFd creates new read / write memory mapping of length n.
+func mmapFd(n, fd, flags int) ([]byte, error) {
+	addr, _, errno := syscall.Syscall6(
+		syscall.SYS_MMAP,
+		0,
+		uintptr(n),
+		syscall.PROT_READ|syscall.PROT_WRITE,
+		uintptr(flags),
+		uintptr(fd),
+		0,
+	)
+	if errno != 0 {
+		return nil, errno
+	}
+	return bytesAt(addr, n), nil
+}
+
+// mremap resizes memory mapping p to n bytes possibly moving it.
+func mremap(p []byte, n int) ([]byte, error) {
+	addr, _, errno := syscall.Syscall6(
+		syscall.SYS_MREMAP,
+		addressOf(p),
+		uintptr(len(p)),
+		uintptr(n),
+		mremapMayMove,
+		0,
+		0,
+	)
+	if errno != 0 {
+		return nil, errno
+	}
+	return bytesAt(addr, n), nil
+}
+
+// munmap deletes memory mapping p.
+func munmap(p []byte) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_MUNMAP,
+		addressOf(p),
+		uintptr(len(p)),
+		0,
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// addressOf returns address of the first byte of the memory mapping p.
+func addressOf(p []byte) uintptr {
+	return uintptr(unsafe.Pointer(&p[0]))
+}
+
+// bytesAt returns slice of length n backed by memory at address addr.
+func bytesAt(addr uintptr, n int) []byte {
+	var p []byte
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&p))
+	h.Data = addr
+	h.Len = n
+	h.Cap = n
+	return p
+}
@@ -0,0 +1,31 @@
+//go:build !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !(linux && (amd64 || arm64 || loong64 || ppc64 || ppc64le || riscv64))
+// +build !darwin
+// +build !dragonfly
+// +build !freebsd
+// +build !netbsd
+// +build !openbsd
+// +build !linux !amd64,!arm64,!loong64,!ppc64,!ppc64le,!riscv64
+
+package flexbuf
+
+import (
+	"errors"
+)
+
+// errNoMmap is returned on platforms without memory mapping support.
+var errNoMmap = errors.New("memory mapping not supported")
+
+// mmap always returns errNoMmap.
+func mmap(n int) ([]byte, error) {
+	return nil, errNoMmap
+}
+
+// mremap always returns errNoMmap.
+func mremap(p []byte, n int) ([]byte, error) {
+	return nil, errNoMmap
+}
+
+// munmap always returns errNoMmap.
+func munmap(p []byte) error {
+	return errNoMmap
+}
@@ -0,0 +1,37 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package flexbuf
+
+import (
+	"syscall"
+)
+
+// mmap creates new anonymous private memory mapping of length n.
+func mmap(n int) ([]byte, error) {
+	return syscall.Mmap(
+		-1,
+		0,
+		n,
+		syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_PRIVATE|syscall.MAP_ANON,
+	)
+}
+
+// mremap resizes memory mapping p to n bytes. Platforms other than Linux
+// do not support resizing mappings in place so the data is copied to
+// the new mapping.
+func mremap(p []byte, n int) ([]byte, error) {
+	tmp, err := mmap(n)
+	if err != nil {
+		return nil, err
+	}
+	copy(tmp, p)
+	_ = munmap(p)
+	return tmp, nil
+}
+
+// munmap deletes memory mapping p.
+func munmap(p []byte) error {
+	return syscall.Munmap(p)
+}
@@ -0,0 +1,69 @@
+package flexbuf
+
+// allocator allocates memory for the Buffer outside of the Go heap.
+type allocator interface {
+	// realloc returns a slice of length n with the contents of p copied
+	// into it. When mapped is true p was returned by a previous call to
+	// realloc and its memory may be reused or released. It returns false
+	// when the allocator can't provide the memory, in which case the
+	// caller should fall back to the Go heap.
+	realloc(p []byte, mapped bool, n int) ([]byte, bool)
+
+	// free releases memory returned by realloc.
+	free(p []byte)
+
+	// shareable returns true if memory returned by realloc may be shared
+	// by buffer clones.
+	shareable() bool
+}
+
+// OffHeap is the constructor option making the buffer allocate memory of
+// at least threshold bytes outside of the Go heap using anonymous memory
+// mappings. Smaller allocations are done on the Go heap as usual. Off-heap
+// memory is not counted towards the Go heap size so huge buffers do not
+// affect garbage collector pacing. On Linux the mapping grows in place
+// with mremap when possible. The memory is released by Close and Release
+// methods, the Buffer must not be used after it's been garbage collected
+// without calling one of them first - the memory will leak.
+//
+// On platforms without memory mapping support the option has no effect.
+func OffHeap(threshold int) func(*Buffer) {
+	return func(b *Buffer) {
+		b.mem = mmapAlloc{min: threshold}
+	}
+}
+
+// mmapAlloc allocates memory using anonymous memory mappings.
+type mmapAlloc struct {
+	// Minimal allocation size served by the allocator.
+	min int
+}
+
+func (a mmapAlloc) realloc(p []byte, mapped bool, n int) ([]byte, bool) {
+	if n < a.min {
+		return nil, false
+	}
+
+	if mapped {
+		tmp, err := mremap(p[:cap(p)], n)
+		if err != nil {
+			return nil, false
+		}
+		return tmp, true
+	}
+
+	tmp, err := mmap(n)
+	if err != nil {
+		return nil, false
+	}
+	copy(tmp, p)
+	return tmp, true
+}
+
+func (a mmapAlloc) free(p []byte) {
+	_ = munmap(p[:cap(p)])
+}
+
+func (a mmapAlloc) shareable() bool {
+	return true
+}
@@ -0,0 +1,109 @@
+package flexbuf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// skipNoMmap skips the test on platforms without memory mapping support.
+func skipNoMmap(t *testing.T) {
+	t.Helper()
+	p, err := mmap(1)
+	if err != nil {
+		t.Skip("memory mapping not supported")
+	}
+	require.NoError(t, munmap(p))
+}
+
+func Test_OffHeap_BelowThreshold(t *testing.T) {
+	// --- Given ---
+	skipNoMmap(t)
+	buf := &Buffer{}
+	OffHeap(1 << 20)(buf)
+
+	// --- When ---
+	_, err := buf.Write([]byte{0, 1, 2})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.False(t, buf.mapped)
+	assert.Exactly(t, []byte{0, 1, 2}, buf.buf)
+}
+
+func Test_OffHeap_Write(t *testing.T) {
+	// --- Given ---
+	skipNoMmap(t)
+	buf := New(OffHeap(1024))
+	data := bytes.Repeat([]byte{0, 1, 2, 3}, 1000)
+
+	// --- When ---
+	n, err := buf.Write(data)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 4000, n)
+	assert.True(t, buf.mapped)
+	assert.Exactly(t, data, buf.buf)
+	assert.NoError(t, buf.Close())
+	assert.False(t, buf.mapped)
+	assert.Nil(t, buf.buf)
+}
+
+func Test_OffHeap_Grow(t *testing.T) {
+	// --- Given ---
+	skipNoMmap(t)
+	buf := New(OffHeap(1024))
+	data := bytes.Repeat([]byte{0, 1, 2, 3}, 1000)
+	_, err := buf.Write(data)
+	require.NoError(t, err)
+
+	// --- When ---
+	for i := 0; i < 100; i++ {
+		_, err = buf.Write(data)
+		require.NoError(t, err)
+	}
+
+	// --- Then ---
+	assert.True(t, buf.mapped)
+	assert.Exactly(t, 404000, buf.Len())
+	assert.Exactly(t, bytes.Repeat(data, 101), buf.buf)
+	assert.NoError(t, buf.Close())
+}
+
+func Test_OffHeap_Truncate(t *testing.T) {
+	// --- Given ---
+	skipNoMmap(t)
+	buf := With([]byte{0, 1, 2}, OffHeap(1024))
+
+	// --- When ---
+	err := buf.Truncate(1 << 20)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.True(t, buf.mapped)
+	assert.Exactly(t, 1<<20, buf.Len())
+	assert.Exactly(t, []byte{0, 1, 2, 0}, buf.buf[:4])
+	assert.Exactly(t, make([]byte, 1<<10), buf.buf[1<<19:1<<19+1<<10])
+	assert.NoError(t, buf.Close())
+}
+
+func Test_OffHeap_Release(t *testing.T) {
+	// --- Given ---
+	skipNoMmap(t)
+	buf := New(OffHeap(1024))
+	data := bytes.Repeat([]byte{0, 1, 2, 3}, 1000)
+	_, err := buf.Write(data)
+	require.NoError(t, err)
+
+	// --- When ---
+	got := buf.Release()
+
+	// --- Then ---
+	assert.Exactly(t, data, got)
+	assert.False(t, buf.mapped)
+	assert.Nil(t, buf.buf)
+	assert.Exactly(t, 0, buf.off)
+}
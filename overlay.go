@@ -0,0 +1,348 @@
+package flexbuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// patchMagic is the magic string starting the Overlay patch.
+const patchMagic = "FLEXBUFP"
+
+// ErrInvalidPatch is returned when patch can't be decoded.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// Overlay presents read-only base of known size as a read / write buffer.
+// Only modified extents are stored in memory, the rest of the data is read
+// from the base. Bytes beyond the base size read as zeros.
+type Overlay struct {
+	// Read-only base.
+	base io.ReaderAt
+	// Base size.
+	baseSize int64
+	// Number of base bytes visible through the overlay.
+	visible int64
+	// Overlay size.
+	size int64
+	// Current offset for read and write operations.
+	off int64
+	// Modified extents.
+	ext extents
+}
+
+// NewOverlay returns new Overlay over base of given size.
+func NewOverlay(base io.ReaderAt, size int64) *Overlay {
+	return &Overlay{
+		base:     base,
+		baseSize: size,
+		visible:  size,
+		size:     size,
+	}
+}
+
+// Write writes the contents of p to the overlay at current offset. The
+// return value n is the length of p; err is always nil.
+func (o *Overlay) Write(p []byte) (int, error) {
+	n, err := o.WriteAt(p, o.off)
+	o.off += int64(n)
+	return n, err
+}
+
+// WriteAt writes len(p) bytes to the overlay starting at byte offset off,
+// growing the overlay as needed. It returns the number of bytes written;
+// err is os.ErrInvalid when off is negative. It does not change the offset.
+func (o *Overlay) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	o.ext.write(p, off)
+	if end := off + int64(len(p)); end > o.size {
+		o.size = end
+	}
+	return len(p), nil
+}
+
+// Read reads the next len(p) bytes from the overlay or until the overlay
+// is drained. The return value is the number of bytes read. If the
+// overlay has no data to return, err is io.EOF (unless len(p) is zero).
+func (o *Overlay) Read(p []byte) (int, error) {
+	if len(p) > 0 && o.off >= o.size {
+		return 0, io.EOF
+	}
+	n, err := o.ReadAt(p, o.off)
+	o.off += int64(n)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from the overlay starting at byte offset off.
+// It returns the number of bytes read and the error, if any. ReadAt
+// always returns a non-nil error when n < len(p). It does not change
+// the offset.
+func (o *Overlay) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	if off >= o.size {
+		return 0, io.EOF
+	}
+
+	want := p
+	if rem := o.size - off; int64(len(want)) > rem {
+		want = want[:rem]
+	}
+
+	if err := o.ext.read(want, off, o.readBase); err != nil {
+		return 0, err
+	}
+
+	if len(want) < len(p) {
+		return len(want), io.EOF
+	}
+	return len(want), nil
+}
+
+// readBase reads len(p) bytes from the base at offset off. Bytes beyond
+// the visible part of the base are zeroed.
+func (o *Overlay) readBase(p []byte, off int64) error {
+	var n int
+	if off < o.visible {
+		n = len(p)
+		if rem := o.visible - off; int64(n) > rem {
+			n = int(rem)
+		}
+		m, err := o.base.ReadAt(p[:n], off)
+		if m < n {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	zeroOutSlice(p[n:])
+	return nil
+}
+
+// Seek sets the offset for the next Read or Write on the overlay to offset,
+// interpreted according to whence: 0 means relative to the origin of the file,
+// 1 means relative to the current offset, and 2 means relative to the end.
+// It returns the new offset and an error (only if calculated offset < 0).
+func (o *Overlay) Seek(offset int64, whence int) (int64, error) {
+	var off int64
+	switch whence {
+	case io.SeekStart:
+		off = offset
+	case io.SeekCurrent:
+		off = o.off + offset
+	case io.SeekEnd:
+		off = o.size + offset
+	}
+
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	o.off = off
+
+	return o.off, nil
+}
+
+// Truncate changes the size of the overlay discarding bytes at offsets
+// greater then size. Bytes added when extending the overlay read as zeros.
+// It does not change the offset. It returns error os.ErrInvalid only when
+// when size is negative.
+func (o *Overlay) Truncate(size int64) error {
+	if size < 0 {
+		return os.ErrInvalid
+	}
+	if size < o.size {
+		o.ext.cut(size)
+		if size < o.visible {
+			o.visible = size
+		}
+	}
+	o.size = size
+	return nil
+}
+
+// Offset returns the current offset.
+func (o *Overlay) Offset() int64 {
+	return o.off
+}
+
+// Size returns the overlay size.
+func (o *Overlay) Size() int64 {
+	return o.size
+}
+
+// Changes returns the byte ranges modified by writes. Size changes made
+// with Truncate are not reported as changes.
+func (o *Overlay) Changes() []Range {
+	return o.ext.ranges()
+}
+
+// Commit writes changes to w which is expected to hold the base contents,
+// typically w is the base itself. Truncated base bytes are zeroed or, if w
+// implements Truncate(size int64) error method, w is truncated to match
+// the overlay size.
+func (o *Overlay) Commit(w io.WriterAt) error {
+	if t, ok := w.(interface{ Truncate(int64) error }); ok {
+		if o.visible < o.baseSize {
+			if err := t.Truncate(o.visible); err != nil {
+				return err
+			}
+		}
+		if o.size != o.visible {
+			if err := t.Truncate(o.size); err != nil {
+				return err
+			}
+		}
+	} else if err := o.zeroTruncated(w); err != nil {
+		return err
+	}
+
+	for _, e := range o.ext {
+		if _, err := w.WriteAt(e.data, e.off); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zeroTruncated writes zeros to w in place of base bytes which are not
+// visible through the overlay.
+func (o *Overlay) zeroTruncated(w io.WriterAt) error {
+	end := o.baseSize
+	if o.size < end {
+		end = o.size
+	}
+
+	var zeros []byte
+	for off := o.visible; off < end; {
+		n := end - off
+		if n > flushChunk {
+			n = flushChunk
+		}
+		if int64(len(zeros)) < n {
+			zeros = make([]byte, n)
+		}
+		if _, err := w.WriteAt(zeros[:n], off); err != nil {
+			return err
+		}
+		off += n
+	}
+	return nil
+}
+
+// WritePatch writes changes to w in a binary format which can be applied
+// to the overlay over the same base using ApplyPatch method. It returns
+// the number of bytes written and an error if any.
+//
+// The patch starts with 8 byte magic string "FLEXBUFP" followed by
+// big endian uint64 values: the number of visible base bytes, the overlay
+// size and the number of extents. Each extent is encoded as uint64
+// offset, uint64 length and the extent data.
+func (o *Overlay) WritePatch(w io.Writer) (int64, error) {
+	hdr := make([]byte, len(patchMagic)+24)
+	copy(hdr, patchMagic)
+	binary.BigEndian.PutUint64(hdr[8:], uint64(o.visible))
+	binary.BigEndian.PutUint64(hdr[16:], uint64(o.size))
+	binary.BigEndian.PutUint64(hdr[24:], uint64(len(o.ext)))
+
+	n, err := w.Write(hdr)
+	total := int64(n)
+	if err != nil {
+		return total, err
+	}
+
+	for _, e := range o.ext {
+		binary.BigEndian.PutUint64(hdr[0:], uint64(e.off))
+		binary.BigEndian.PutUint64(hdr[8:], uint64(len(e.data)))
+		n, err = w.Write(hdr[:16])
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		n, err = w.Write(e.data)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// ApplyPatch reads the patch written by WritePatch from r and applies it
+// to the overlay. It returns ErrInvalidPatch if the patch is malformed.
+// The whole patch is read before it's applied so the overlay is not
+// changed when reading or decoding the patch fails.
+func (o *Overlay) ApplyPatch(r io.Reader) error {
+	hdr := make([]byte, len(patchMagic)+24)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return patchErr(err)
+	}
+	if string(hdr[:8]) != patchMagic {
+		return ErrInvalidPatch
+	}
+
+	visible := int64(binary.BigEndian.Uint64(hdr[8:]))
+	size := int64(binary.BigEndian.Uint64(hdr[16:]))
+	cnt := binary.BigEndian.Uint64(hdr[24:])
+	if visible < 0 || size < 0 {
+		return ErrInvalidPatch
+	}
+
+	var chunks []extent
+	for i := uint64(0); i < cnt; i++ {
+		if _, err := io.ReadFull(r, hdr[:16]); err != nil {
+			return patchErr(err)
+		}
+		off := int64(binary.BigEndian.Uint64(hdr[0:]))
+		l := int64(binary.BigEndian.Uint64(hdr[8:]))
+		if off < 0 || l < 0 || l > size-off {
+			return ErrInvalidPatch
+		}
+
+		// Read extent in chunks so the malformed length
+		// does not cause huge allocation.
+		for l > 0 {
+			n := l
+			if n > flushChunk {
+				n = flushChunk
+			}
+			data := make([]byte, n)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return patchErr(err)
+			}
+			chunks = append(chunks, extent{off: off, data: data})
+			off += n
+			l -= n
+		}
+	}
+
+	if err := o.Truncate(visible); err != nil {
+		return err
+	}
+	if err := o.Truncate(size); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if _, err := o.WriteAt(c.data, c.off); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// patchErr maps errors encountered when reading truncated patch
+// to ErrInvalidPatch.
+func patchErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrInvalidPatch
+	}
+	return err
+}
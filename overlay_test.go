@@ -0,0 +1,227 @@
+package flexbuf
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Overlay_Read_Base(t *testing.T) {
+	// --- Given ---
+	data := testData(10)
+	o := NewOverlay(bytes.NewReader(data), 10)
+
+	// --- When ---
+	got, err := ioutil.ReadAll(o)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, data, got)
+	assert.Empty(t, o.Changes())
+}
+
+func Test_Overlay_WriteAt(t *testing.T) {
+	// --- Given ---
+	data := testData(10)
+	o := NewOverlay(bytes.NewReader(data), 10)
+
+	// --- When ---
+	_, err := o.WriteAt([]byte{100, 101}, 2)
+	require.NoError(t, err)
+	_, err = o.WriteAt([]byte{102}, 8)
+	require.NoError(t, err)
+
+	// --- Then ---
+	got := make([]byte, 10)
+	n, err := o.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Exactly(t, 10, n)
+	assert.Exactly(t, []byte{0, 1, 100, 101, 4, 5, 6, 7, 102, 9}, got)
+	assert.Exactly(t, []Range{{2, 2}, {8, 1}}, o.Changes())
+	assert.Exactly(t, testData(10), data)
+}
+
+func Test_Overlay_Write_Extend(t *testing.T) {
+	// --- Given ---
+	o := NewOverlay(bytes.NewReader(testData(4)), 4)
+	_, err := o.Seek(-1, io.SeekEnd)
+	require.NoError(t, err)
+
+	// --- When ---
+	n, err := o.Write([]byte{100, 101, 102})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 3, n)
+	assert.Exactly(t, int64(6), o.Size())
+	assert.Exactly(t, int64(6), o.Offset())
+
+	got := make([]byte, 8)
+	n, err = o.ReadAt(got, 0)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Exactly(t, 6, n)
+	assert.Exactly(t, []byte{0, 1, 2, 100, 101, 102}, got[:n])
+}
+
+func Test_Overlay_Truncate(t *testing.T) {
+	// --- Given ---
+	o := NewOverlay(bytes.NewReader(testData(10)), 10)
+	_, err := o.WriteAt([]byte{100, 101}, 2)
+	require.NoError(t, err)
+
+	// --- When ---
+	require.NoError(t, o.Truncate(3))
+	require.NoError(t, o.Truncate(6))
+
+	// --- Then ---
+	got := make([]byte, 6)
+	_, err = o.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1, 100, 0, 0, 0}, got)
+	assert.Exactly(t, []Range{{2, 1}}, o.Changes())
+}
+
+func Test_Overlay_Commit_File(t *testing.T) {
+	// --- Given ---
+	fil := TempFile(t, os.O_RDWR, testData(10))
+	o := NewOverlay(fil, 10)
+	_, err := o.WriteAt([]byte{100, 101}, 2)
+	require.NoError(t, err)
+	require.NoError(t, o.Truncate(5))
+	require.NoError(t, o.Truncate(7))
+
+	// --- When ---
+	err = o.Commit(fil)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	got, err := ioutil.ReadFile(fil.Name())
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1, 100, 101, 4, 0, 0}, got)
+}
+
+func Test_Overlay_Commit_WriterAt(t *testing.T) {
+	// --- Given ---
+	dst := With(testData(10))
+	o := NewOverlay(bytes.NewReader(testData(10)), 10)
+	_, err := o.WriteAt([]byte{100}, 1)
+	require.NoError(t, err)
+	require.NoError(t, o.Truncate(5))
+	require.NoError(t, o.Truncate(7))
+
+	// --- When ---
+	err = o.Commit(struct{ io.WriterAt }{dst})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 100, 2, 3, 4, 0, 0, 7, 8, 9}, dst.buf)
+}
+
+func Test_Overlay_Patch(t *testing.T) {
+	// --- Given ---
+	base := testData(10)
+	o := NewOverlay(bytes.NewReader(base), 10)
+	_, err := o.WriteAt([]byte{100, 101}, 2)
+	require.NoError(t, err)
+	require.NoError(t, o.Truncate(8))
+	_, err = o.WriteAt([]byte{102}, 11)
+	require.NoError(t, err)
+
+	patch := &bytes.Buffer{}
+	n, err := o.WritePatch(patch)
+	require.NoError(t, err)
+	require.Exactly(t, int64(patch.Len()), n)
+
+	// --- When ---
+	dst := NewOverlay(bytes.NewReader(base), 10)
+	err = dst.ApplyPatch(patch)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, o.Size(), dst.Size())
+	assert.Exactly(t, o.Changes(), dst.Changes())
+	exp, _ := ioutil.ReadAll(o)
+	got, _ := ioutil.ReadAll(dst)
+	assert.Exactly(t, exp, got)
+	assert.Exactly(t, []byte{0, 1, 100, 101, 4, 5, 6, 7, 0, 0, 0, 102}, got)
+}
+
+func Test_Overlay_ApplyPatch_Invalid(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		patch []byte
+	}{
+		{"empty", nil},
+		{"magic", []byte("XXXXXXXX000000000000000000000000")},
+		{"truncated", []byte("FLEXBUFP")},
+		{
+			"extent",
+			append([]byte(patchMagic), []byte{
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 5,
+			}...),
+		},
+		{
+			"extent overflow",
+			append([]byte(patchMagic), []byte{
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0x40, 0, 0, 0, 0, 0, 0, 0,
+				0x40, 0, 0, 0, 0, 0, 0, 0,
+			}...),
+		},
+		{
+			"extent data",
+			append([]byte(patchMagic), []byte{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 8,
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 4,
+				9, 9,
+			}...),
+		},
+		{
+			"second extent",
+			append([]byte(patchMagic), []byte{
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 8,
+				0, 0, 0, 0, 0, 0, 0, 2,
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 1,
+				9,
+				0, 0, 0, 0, 0, 0, 0, 8,
+				0, 0, 0, 0, 0, 0, 0, 1,
+			}...),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			o := NewOverlay(bytes.NewReader([]byte{1, 2, 3, 4}), 4)
+
+			// --- When ---
+			err := o.ApplyPatch(bytes.NewReader(tc.patch))
+
+			// --- Then ---
+			assert.ErrorIs(t, err, ErrInvalidPatch)
+			assert.Exactly(t, int64(4), o.Size())
+			assert.Empty(t, o.Changes())
+			got := make([]byte, 4)
+			_, err = o.ReadAt(got, 0)
+			assert.NoError(t, err)
+			assert.Exactly(t, []byte{1, 2, 3, 4}, got)
+		})
+	}
+}
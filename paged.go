@@ -0,0 +1,427 @@
+package flexbuf
+
+import (
+	"container/list"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultPageSize is the default Paged page size.
+const DefaultPageSize = 4096
+
+// PageSize is the Paged constructor option setting the page size.
+// It will panic with ErrOutOfBounds if size is not a positive number.
+func PageSize(size int) func(*Paged) {
+	return func(pg *Paged) {
+		if size <= 0 {
+			panic(ErrOutOfBounds)
+		}
+		pg.psize = size
+	}
+}
+
+// ReadAhead is the Paged constructor option setting the number of pages
+// following the missing page which are fetched from the source together
+// with it.
+func ReadAhead(pages int) func(*Paged) {
+	return func(pg *Paged) {
+		pg.ahead = pages
+	}
+}
+
+// MaxPages is the Paged constructor option setting the maximum number of
+// clean (not modified) pages kept in memory. When the limit is reached
+// the least recently used clean page is evicted. Zero means no limit.
+func MaxPages(pages int) func(*Paged) {
+	return func(pg *Paged) {
+		pg.max = pages
+	}
+}
+
+// page represents single Paged page.
+type page struct {
+	// Page index.
+	idx int64
+	// Page data, always the page size long.
+	data []byte
+	// True when the page was modified.
+	dirty bool
+	// Position on the LRU list, nil for dirty pages.
+	elem *list.Element
+}
+
+// Paged is a buffer over an io.ReaderAt source which fetches fixed size
+// pages from the source only when they are first accessed. Writes are
+// kept in memory as dirty pages and never reach the source. Bytes beyond
+// the source size read as zeros. Paged is safe for concurrent use.
+type Paged struct {
+	// Guards all the fields below, reads change pages and the LRU list too.
+	mu sync.Mutex
+	// Data source.
+	src io.ReaderAt
+	// Number of bytes which may be fetched from the source.
+	srcSize int64
+	// Buffer size.
+	size int64
+	// Current offset for read and write operations.
+	off int64
+	// Page size.
+	psize int
+	// Number of pages to read ahead.
+	ahead int
+	// Maximum number of clean pages, zero means unlimited.
+	max int
+	// Pages in memory by index.
+	pages map[int64]*page
+	// Clean pages, the most recently used at the front.
+	lru *list.List
+	// Write-back destination, nil when write-back is disabled.
+	dst io.WriterAt
+	// Modified byte ranges.
+	dirty ranges
+	// Number of modified bytes triggering write-back, zero means never.
+	maxDirty int64
+	// True when the buffer was truncated since the last write-back.
+	truncated bool
+}
+
+// NewPaged returns new Paged buffer over the src of given size.
+func NewPaged(src io.ReaderAt, size int64, opts ...func(*Paged)) *Paged {
+	pg := &Paged{
+		src:     src,
+		srcSize: size,
+		size:    size,
+		psize:   DefaultPageSize,
+		pages:   make(map[int64]*page),
+		lru:     list.New(),
+	}
+
+	for _, opt := range opts {
+		opt(pg)
+	}
+
+	return pg
+}
+
+// Write writes the contents of p to the buffer at current offset. It
+// returns the number of bytes written and an error if fetching the
+// pages from the source failed.
+func (pg *Paged) Write(p []byte) (int, error) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	n, err := pg.writeAt(p, pg.off)
+	pg.off += int64(n)
+	return n, err
+}
+
+// WriteAt writes len(p) bytes to the buffer starting at byte offset off,
+// growing the buffer as needed. Pages partially overwritten are fetched
+// from the source first. It does not change the offset. When fetching
+// a page fails the bytes written before are kept.
+func (pg *Paged) WriteAt(p []byte, off int64) (int, error) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return pg.writeAt(p, off)
+}
+
+// writeAt writes len(p) bytes starting at byte offset off. See WriteAt.
+func (pg *Paged) writeAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+
+	var n int
+	var err error
+	for n < len(p) {
+		pos := off + int64(n)
+		idx, po := pg.locate(pos)
+
+		var pp *page
+		if po == 0 && len(p)-n >= pg.psize {
+			pp = pg.blank(idx) // Whole page overwritten.
+		} else if pp, err = pg.page(idx, false); err != nil {
+			break
+		}
+
+		n += copy(pp.data[po:], p[n:])
+		pg.markDirty(pp)
+	}
+
+	if n > 0 {
+		if end := off + int64(n); end > pg.size {
+			pg.size = end
+		}
+		pg.dirty.add(Range{Off: off, Len: int64(n)})
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if pg.dst != nil && pg.maxDirty > 0 && pg.dirty.size() > pg.maxDirty {
+		return n, pg.flush()
+	}
+	return n, nil
+}
+
+// Read reads the next len(p) bytes from the buffer or until the buffer
+// is drained. The return value is the number of bytes read. If the
+// buffer has no data to return, err is io.EOF (unless len(p) is zero).
+func (pg *Paged) Read(p []byte) (int, error) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	if len(p) > 0 && pg.off >= pg.size {
+		return 0, io.EOF
+	}
+	n, err := pg.readAt(p, pg.off, true)
+	pg.off += int64(n)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from the buffer starting at byte offset off.
+// It returns the number of bytes read and the error, if any. ReadAt
+// always returns a non-nil error when n < len(p). It does not change
+// the offset.
+func (pg *Paged) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return pg.readAt(p, off, false)
+}
+
+// readAt reads len(p) bytes starting at byte offset off. When ahead is
+// true missing pages are fetched with read-ahead.
+func (pg *Paged) readAt(p []byte, off int64, ahead bool) (int, error) {
+	if off >= pg.size {
+		return 0, io.EOF
+	}
+
+	want := p
+	if rem := pg.size - off; int64(len(want)) > rem {
+		want = want[:rem]
+	}
+
+	var n int
+	for n < len(want) {
+		idx, po := pg.locate(off + int64(n))
+		pp, err := pg.page(idx, ahead)
+		if err != nil {
+			return n, err
+		}
+		n += copy(want[n:], pp.data[po:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek sets the offset for the next Read or Write on the buffer to offset,
+// interpreted according to whence: 0 means relative to the origin of the file,
+// 1 means relative to the current offset, and 2 means relative to the end.
+// It returns the new offset and an error (only if calculated offset < 0).
+func (pg *Paged) Seek(offset int64, whence int) (int64, error) {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	var off int64
+	switch whence {
+	case io.SeekStart:
+		off = offset
+	case io.SeekCurrent:
+		off = pg.off + offset
+	case io.SeekEnd:
+		off = pg.size + offset
+	}
+
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	pg.off = off
+
+	return pg.off, nil
+}
+
+// Truncate changes the size of the buffer discarding bytes at offsets
+// greater then size. Bytes added when extending the buffer read as zeros.
+// It does not change the offset. It returns error os.ErrInvalid only when
+// when size is negative.
+func (pg *Paged) Truncate(size int64) error {
+	if size < 0 {
+		return os.ErrInvalid
+	}
+
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	if size < pg.size {
+		for idx, pp := range pg.pages {
+			if idx*int64(pg.psize) >= size {
+				pg.drop(pp)
+			}
+		}
+
+		// Zero out the tail of the last page.
+		idx, po := pg.locate(size)
+		if pp, ok := pg.pages[idx]; ok {
+			zeroOutSlice(pp.data[po:])
+		}
+
+		if size < pg.srcSize {
+			pg.srcSize = size
+		}
+		pg.dirty.cut(size)
+	}
+
+	pg.size = size
+	pg.truncated = true
+	return nil
+}
+
+// Offset returns the current offset.
+func (pg *Paged) Offset() int64 {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return pg.off
+}
+
+// Size returns the buffer size.
+func (pg *Paged) Size() int64 {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return pg.size
+}
+
+// Pages returns the number of pages in memory.
+func (pg *Paged) Pages() int {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	return len(pg.pages)
+}
+
+// Close drops all pages, including the modified ones which were not
+// written back, and sets the offset to zero. It always returns nil error.
+func (pg *Paged) Close() error {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+	pg.off = 0
+	pg.pages = make(map[int64]*page)
+	pg.lru.Init()
+	pg.dirty = nil
+	return nil
+}
+
+// locate returns the index of the page holding byte at offset off and the
+// offset of that byte within the page.
+func (pg *Paged) locate(off int64) (int64, int) {
+	return off / int64(pg.psize), int(off % int64(pg.psize))
+}
+
+// page returns page with given index fetching it from the source if needed.
+// When ahead is true the read-ahead pages are fetched as well.
+func (pg *Paged) page(idx int64, ahead bool) (*page, error) {
+	if pp, ok := pg.pages[idx]; ok {
+		if pp.elem != nil {
+			pg.lru.MoveToFront(pp.elem)
+		}
+		return pp, nil
+	}
+
+	// Number of missing consecutive pages to fetch.
+	cnt := 1
+	if ahead {
+		for cnt <= pg.ahead {
+			if _, ok := pg.pages[idx+int64(cnt)]; ok {
+				break
+			}
+			cnt++
+		}
+	}
+
+	if err := pg.fetch(idx, cnt); err != nil {
+		return nil, err
+	}
+	pg.evict()
+	return pg.pages[idx], nil
+}
+
+// fetch reads cnt pages starting with page idx from the source.
+func (pg *Paged) fetch(idx int64, cnt int) error {
+	data := make([]byte, cnt*pg.psize)
+
+	start := idx * int64(pg.psize)
+	if start < pg.srcSize {
+		want := data
+		if rem := pg.srcSize - start; int64(len(want)) > rem {
+			want = want[:rem]
+		}
+		n, err := pg.src.ReadAt(want, start)
+		if n < len(want) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+
+	// Add pages in reverse so the requested page ends up
+	// as the most recently used one.
+	for i := cnt - 1; i >= 0; i-- {
+		pp := &page{
+			idx:  idx + int64(i),
+			data: data[i*pg.psize : (i+1)*pg.psize : (i+1)*pg.psize],
+		}
+		pp.elem = pg.lru.PushFront(pp)
+		pg.pages[pp.idx] = pp
+	}
+
+	return nil
+}
+
+// blank returns page with given index without fetching it from the source.
+// If the page does not exist it's created with zero value data.
+func (pg *Paged) blank(idx int64) *page {
+	if pp, ok := pg.pages[idx]; ok {
+		return pp
+	}
+	pp := &page{
+		idx:  idx,
+		data: make([]byte, pg.psize),
+	}
+	pp.elem = pg.lru.PushFront(pp)
+	pg.pages[idx] = pp
+	return pp
+}
+
+// markDirty marks the page as modified removing it from the LRU list.
+func (pg *Paged) markDirty(pp *page) {
+	if pp.elem != nil {
+		pg.lru.Remove(pp.elem)
+		pp.elem = nil
+	}
+	pp.dirty = true
+}
+
+// drop removes page from memory.
+func (pg *Paged) drop(pp *page) {
+	if pp.elem != nil {
+		pg.lru.Remove(pp.elem)
+		pp.elem = nil
+	}
+	delete(pg.pages, pp.idx)
+}
+
+// evict drops the least recently used clean pages above the limit.
+func (pg *Paged) evict() {
+	if pg.max <= 0 {
+		return
+	}
+	for pg.lru.Len() > pg.max {
+		pg.drop(pg.lru.Back().Value.(*page))
+	}
+}
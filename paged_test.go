@@ -0,0 +1,315 @@
+package flexbuf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReaderAt counts calls to ReadAt method.
+type countingReaderAt struct {
+	r     io.ReaderAt
+	calls int
+	bytes int
+	err   error
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.calls++
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.r.ReadAt(p, off)
+	c.bytes += n
+	return n, err
+}
+
+// testData returns n bytes of test data.
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+func Test_Paged_ReadAt_Lazy(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	src := &countingReaderAt{r: bytes.NewReader(data)}
+	pg := NewPaged(src, 100, PageSize(10))
+
+	// --- When ---
+	got := make([]byte, 5)
+	n, err := pg.ReadAt(got, 42)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 5, n)
+	assert.Exactly(t, data[42:47], got)
+	assert.Exactly(t, 1, src.calls)
+	assert.Exactly(t, 10, src.bytes)
+	assert.Exactly(t, 1, pg.Pages())
+	assert.Exactly(t, int64(0), pg.Offset())
+}
+
+func Test_Paged_ReadAt_Cached(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	src := &countingReaderAt{r: bytes.NewReader(data)}
+	pg := NewPaged(src, 100, PageSize(10))
+
+	// --- When ---
+	got := make([]byte, 5)
+	_, err := pg.ReadAt(got, 42)
+	require.NoError(t, err)
+	_, err = pg.ReadAt(got, 45)
+	require.NoError(t, err)
+
+	// --- Then ---
+	assert.Exactly(t, data[45:50], got)
+	assert.Exactly(t, 1, src.calls)
+}
+
+func Test_Paged_ReadAt_BeyondSize(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	pg := NewPaged(bytes.NewReader(data), 100, PageSize(16))
+
+	// --- When ---
+	got := make([]byte, 10)
+	n, err := pg.ReadAt(got, 95)
+
+	// --- Then ---
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Exactly(t, 5, n)
+	assert.Exactly(t, data[95:], got[:5])
+}
+
+func Test_Paged_ReadAt_SourceError(t *testing.T) {
+	// --- Given ---
+	src := &countingReaderAt{err: errors.New("test")}
+	pg := NewPaged(src, 100, PageSize(10))
+
+	// --- When ---
+	n, err := pg.ReadAt(make([]byte, 5), 0)
+
+	// --- Then ---
+	assert.EqualError(t, err, "test")
+	assert.Exactly(t, 0, n)
+	assert.Exactly(t, 0, pg.Pages())
+}
+
+func Test_Paged_Read_All(t *testing.T) {
+	// --- Given ---
+	data := testData(1000)
+	pg := NewPaged(bytes.NewReader(data), 1000, PageSize(64))
+
+	// --- When ---
+	got, err := ioutil.ReadAll(pg)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, data, got)
+	assert.Exactly(t, int64(1000), pg.Offset())
+}
+
+func Test_Paged_Read_ReadAhead(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	src := &countingReaderAt{r: bytes.NewReader(data)}
+	pg := NewPaged(src, 100, PageSize(10), ReadAhead(3))
+
+	// --- When ---
+	got := make([]byte, 35)
+	n, err := pg.Read(got)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 35, n)
+	assert.Exactly(t, data[:35], got)
+	assert.Exactly(t, 1, src.calls)
+	assert.Exactly(t, 4, pg.Pages())
+}
+
+func Test_Paged_MaxPages(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	src := &countingReaderAt{r: bytes.NewReader(data)}
+	pg := NewPaged(src, 100, PageSize(10), MaxPages(2))
+
+	// --- When ---
+	p := make([]byte, 1)
+	for _, off := range []int64{0, 10, 0, 20, 10} {
+		_, err := pg.ReadAt(p, off)
+		require.NoError(t, err)
+	}
+
+	// --- Then ---
+	assert.Exactly(t, 2, pg.Pages())
+	assert.Exactly(t, 4, src.calls)
+}
+
+func Test_Paged_MaxPages_KeepsDirty(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	pg := NewPaged(bytes.NewReader(data), 100, PageSize(10), MaxPages(1))
+
+	// --- When ---
+	_, err := pg.WriteAt([]byte{200}, 5)
+	require.NoError(t, err)
+	_, err = pg.WriteAt([]byte{201}, 15)
+	require.NoError(t, err)
+	_, err = pg.ReadAt(make([]byte, 1), 25)
+	require.NoError(t, err)
+	_, err = pg.ReadAt(make([]byte, 1), 35)
+	require.NoError(t, err)
+
+	// --- Then ---
+	assert.Exactly(t, 3, pg.Pages())
+	got := make([]byte, 20)
+	_, err = pg.ReadAt(got, 0)
+	assert.NoError(t, err)
+	exp := testData(20)
+	exp[5] = 200
+	exp[15] = 201
+	assert.Exactly(t, exp, got)
+}
+
+func Test_Paged_WriteAt_FullPage(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	src := &countingReaderAt{r: bytes.NewReader(data)}
+	pg := NewPaged(src, 100, PageSize(10))
+
+	// --- When ---
+	n, err := pg.WriteAt(bytes.Repeat([]byte{255}, 15), 10)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 15, n)
+	assert.Exactly(t, 1, src.calls) // Only second page is partial.
+
+	got := make([]byte, 30)
+	_, err = pg.ReadAt(got, 0)
+	assert.NoError(t, err)
+	exp := append(testData(10), bytes.Repeat([]byte{255}, 15)...)
+	exp = append(exp, data[25:30]...)
+	assert.Exactly(t, exp, got)
+}
+
+func Test_Paged_WriteAt_SourceError(t *testing.T) {
+	// --- Given ---
+	src := &countingReaderAt{err: errors.New("test")}
+	pg := NewPaged(src, 100, PageSize(10))
+
+	// --- When ---
+	n, err := pg.WriteAt(bytes.Repeat([]byte{255}, 15), 0)
+
+	// --- Then ---
+	assert.EqualError(t, err, "test")
+	assert.Exactly(t, 10, n) // First page overwritten without fetching.
+	assert.Exactly(t, []Range{{Off: 0, Len: 10}}, pg.Dirty())
+	assert.Exactly(t, int64(100), pg.Size())
+}
+
+func Test_Paged_ReadAt_Concurrent(t *testing.T) {
+	// --- Given ---
+	data := testData(100)
+	pg := NewPaged(bytes.NewReader(data), 100, PageSize(10), MaxPages(2))
+
+	// --- When ---
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got := make([]byte, 10)
+			for j := 0; j < 100; j++ {
+				off := int64((i + j) % 10 * 10)
+				_, err := pg.ReadAt(got, off)
+				assert.NoError(t, err)
+				assert.Exactly(t, data[off:off+10], got)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// --- Then ---
+	assert.Exactly(t, 2, pg.Pages())
+}
+
+func Test_Paged_Write_Extend(t *testing.T) {
+	// --- Given ---
+	data := testData(10)
+	pg := NewPaged(bytes.NewReader(data), 10, PageSize(4))
+	_, err := pg.Seek(-2, io.SeekEnd)
+	require.NoError(t, err)
+
+	// --- When ---
+	n, err := pg.Write([]byte{100, 101, 102, 103})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 4, n)
+	assert.Exactly(t, int64(12), pg.Size())
+	assert.Exactly(t, int64(12), pg.Offset())
+
+	_, err = pg.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(pg)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 100, 101, 102, 103}, got)
+}
+
+func Test_Paged_Truncate(t *testing.T) {
+	// --- Given ---
+	data := testData(20)
+	pg := NewPaged(bytes.NewReader(data), 20, PageSize(4))
+	_, err := pg.ReadAt(make([]byte, 20), 0)
+	require.NoError(t, err)
+
+	// --- When ---
+	require.NoError(t, pg.Truncate(6))
+	require.NoError(t, pg.Truncate(10))
+
+	// --- Then ---
+	assert.Exactly(t, int64(10), pg.Size())
+	got := make([]byte, 10)
+	_, err = pg.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1, 2, 3, 4, 5, 0, 0, 0, 0}, got)
+}
+
+func Test_Paged_Truncate_NotLoaded(t *testing.T) {
+	// --- Given ---
+	data := testData(20)
+	pg := NewPaged(bytes.NewReader(data), 20, PageSize(4))
+
+	// --- When ---
+	require.NoError(t, pg.Truncate(6))
+	require.NoError(t, pg.Truncate(20))
+
+	// --- Then ---
+	got := make([]byte, 20)
+	_, err := pg.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Exactly(t, append([]byte{0, 1, 2, 3, 4, 5}, make([]byte, 14)...), got)
+}
+
+func Test_Paged_Truncate_Error(t *testing.T) {
+	// --- Given ---
+	pg := NewPaged(bytes.NewReader(nil), 0)
+
+	// --- When ---
+	err := pg.Truncate(-1)
+
+	// --- Then ---
+	assert.Error(t, err)
+}
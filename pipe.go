@@ -0,0 +1,242 @@
+package flexbuf
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Capacity is the Pipe constructor option limiting the number of buffered
+// bytes to n. Writers block when the pipe is full until readers consume
+// some data. Zero means unbounded capacity.
+func Capacity(n int) func(*Pipe) {
+	return func(p *Pipe) {
+		p.max = n
+	}
+}
+
+// Pipe is an in-memory pipe backed by the Buffer. Unlike Buffer its reads
+// block until data is written or the write side of the pipe is closed
+// with CloseWrite. Consumed data is discarded so the memory is reused.
+// Pipe is safe for concurrent use by multiple goroutines.
+type Pipe struct {
+	// Guards all the fields.
+	mu sync.Mutex
+	// Buffered data, the offset points to the first unread byte.
+	buf Buffer
+	// Maximum number of buffered bytes, zero means unbounded.
+	max int
+	// Set by CloseWrite.
+	wclosed bool
+	// Set by Close.
+	closed bool
+	// Read and write deadlines, zero means no deadline.
+	rdl, wdl time.Time
+	// Closed and set to nil when the pipe state changes.
+	notify chan struct{}
+}
+
+// NewPipe returns new Pipe.
+func NewPipe(opts ...func(*Pipe)) *Pipe {
+	p := &Pipe{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.max < 0 {
+		p.max = 0
+	}
+	return p
+}
+
+// Read reads up to len(b) bytes from the pipe blocking until at least one
+// byte is available. It returns io.EOF when the pipe is empty and its
+// write side is closed, io.ErrClosedPipe when the pipe is closed and
+// os.ErrDeadlineExceeded when the read deadline passes.
+func (p *Pipe) Read(b []byte) (int, error) {
+	return p.ReadContext(context.Background(), b)
+}
+
+// ReadContext is like Read but it returns the context error when the
+// context is done before any data is available.
+func (p *Pipe) ReadContext(ctx context.Context, b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		switch {
+		case p.closed:
+			return 0, io.ErrClosedPipe
+		case len(b) == 0:
+			return 0, nil
+		case p.buf.off < len(p.buf.buf):
+			n, _ := p.buf.Read(b)
+			if p.buf.off == len(p.buf.buf) {
+				// Drained, start from the beginning.
+				p.buf.off = 0
+				p.buf.buf = p.buf.buf[:0]
+			}
+			p.changed()
+			return n, nil
+		case p.wclosed:
+			return 0, io.EOF
+		}
+
+		if err := p.wait(ctx, &p.rdl); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write writes len(b) bytes to the pipe. When the pipe has bounded
+// capacity Write blocks until all the data is buffered. It returns the
+// number of bytes written and io.ErrClosedPipe when the pipe is closed
+// or its write side is closed and os.ErrDeadlineExceeded when the write
+// deadline passes.
+func (p *Pipe) Write(b []byte) (int, error) {
+	return p.WriteContext(context.Background(), b)
+}
+
+// WriteContext is like Write but it returns the context error when the
+// context is done before all the data is buffered.
+func (p *Pipe) WriteContext(ctx context.Context, b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var n int
+	for {
+		if p.closed || p.wclosed {
+			return n, io.ErrClosedPipe
+		}
+		if n == len(b) {
+			return n, nil
+		}
+
+		m := len(b) - n
+		if p.max > 0 {
+			if free := p.max - p.size(); free < m {
+				m = free
+			}
+		}
+
+		if m > 0 {
+			p.compact(m)
+			p.buf.buf = append(p.buf.buf, b[n:n+m]...)
+			n += m
+			p.changed()
+			continue
+		}
+
+		if err := p.wait(ctx, &p.wdl); err != nil {
+			return n, err
+		}
+	}
+}
+
+// compact moves unread data to the beginning of the buffer when appending
+// n bytes would grow it.
+func (p *Pipe) compact(n int) {
+	b := &p.buf
+	if b.off == 0 || len(b.buf)+n <= cap(b.buf) {
+		return
+	}
+	l := copy(b.buf, b.buf[b.off:])
+	b.buf = b.buf[:l]
+	b.off = 0
+}
+
+// wait waits for the pipe state change, the deadline pointed by dl or the
+// context. It must be called with the lock held.
+func (p *Pipe) wait(ctx context.Context, dl *time.Time) error {
+	var timeout <-chan time.Time
+	if !dl.IsZero() {
+		d := time.Until(*dl)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	if p.notify == nil {
+		p.notify = make(chan struct{})
+	}
+	ch := p.notify
+	p.mu.Unlock()
+	defer p.mu.Lock()
+
+	// The deadline is checked again by the caller's next wait call
+	// as it may have been changed in the meantime.
+	select {
+	case <-ch:
+	case <-timeout:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// changed wakes up goroutines waiting for the pipe state change. It must
+// be called with the lock held.
+func (p *Pipe) changed() {
+	if p.notify != nil {
+		close(p.notify)
+		p.notify = nil
+	}
+}
+
+// size returns the number of buffered bytes. It must be called with the
+// lock held.
+func (p *Pipe) size() int {
+	return len(p.buf.buf) - p.buf.off
+}
+
+// Len returns the number of bytes which can be read without blocking.
+func (p *Pipe) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size()
+}
+
+// SetReadDeadline sets the deadline for pending and future Read calls.
+// A zero value for t means Read will not time out.
+func (p *Pipe) SetReadDeadline(t time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rdl = t
+	p.changed()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for pending and future Write calls.
+// A zero value for t means Write will not time out.
+func (p *Pipe) SetWriteDeadline(t time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.wdl = t
+	p.changed()
+	return nil
+}
+
+// CloseWrite closes the write side of the pipe. Readers get io.EOF after
+// the buffered data is consumed. It always returns nil error.
+func (p *Pipe) CloseWrite() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.wclosed = true
+	p.changed()
+	return nil
+}
+
+// Close closes the pipe discarding buffered data. Pending and future
+// reads and writes return io.ErrClosedPipe. It always returns nil error.
+func (p *Pipe) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.buf = Buffer{}
+	p.changed()
+	return nil
+}
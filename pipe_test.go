@@ -0,0 +1,220 @@
+package flexbuf
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Pipe_ReadWrite(t *testing.T) {
+	// --- Given ---
+	p := NewPipe()
+
+	// --- When ---
+	n, err := p.Write([]byte{0, 1, 2})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 3, n)
+	assert.Exactly(t, 3, p.Len())
+
+	got := make([]byte, 2)
+	n, err = p.Read(got)
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, []byte{0, 1}, got)
+	assert.Exactly(t, 1, p.Len())
+}
+
+func Test_Pipe_Read_Blocks(t *testing.T) {
+	// --- Given ---
+	p := NewPipe()
+	data := testData(10000)
+
+	go func() {
+		for i := 0; i < len(data); i += 100 {
+			_, _ = p.Write(data[i : i+100])
+		}
+		_ = p.CloseWrite()
+	}()
+
+	// --- When ---
+	got, err := ioutil.ReadAll(p)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, data, got)
+}
+
+func Test_Pipe_CloseWrite(t *testing.T) {
+	// --- Given ---
+	p := NewPipe()
+	_, _ = p.Write([]byte{0, 1})
+
+	// --- When ---
+	require.NoError(t, p.CloseWrite())
+
+	// --- Then ---
+	n, err := p.Write([]byte{2})
+	assert.Exactly(t, io.ErrClosedPipe, err)
+	assert.Exactly(t, 0, n)
+
+	got := make([]byte, 3)
+	n, err = p.Read(got)
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+
+	n, err = p.Read(got)
+	assert.Exactly(t, io.EOF, err)
+	assert.Exactly(t, 0, n)
+}
+
+func Test_Pipe_Close(t *testing.T) {
+	// --- Given ---
+	p := NewPipe()
+	done := make(chan error)
+	go func() {
+		_, err := p.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	// --- When ---
+	require.NoError(t, p.Close())
+
+	// --- Then ---
+	assert.Exactly(t, io.ErrClosedPipe, <-done)
+	_, err := p.Write([]byte{0})
+	assert.Exactly(t, io.ErrClosedPipe, err)
+}
+
+func Test_Pipe_ReadDeadline(t *testing.T) {
+	// --- Given ---
+	p := NewPipe()
+	require.NoError(t, p.SetReadDeadline(time.Now().Add(20*time.Millisecond)))
+
+	// --- When ---
+	n, err := p.Read(make([]byte, 1))
+
+	// --- Then ---
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Exactly(t, 0, n)
+
+	// Clearing the deadline makes the pipe usable again.
+	require.NoError(t, p.SetReadDeadline(time.Time{}))
+	_, _ = p.Write([]byte{1})
+	n, err = p.Read(make([]byte, 1))
+	assert.NoError(t, err)
+	assert.Exactly(t, 1, n)
+}
+
+func Test_Pipe_ReadDeadline_Pending(t *testing.T) {
+	// --- Given ---
+	p := NewPipe()
+	done := make(chan error)
+	go func() {
+		_, err := p.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	// --- When ---
+	require.NoError(t, p.SetReadDeadline(time.Now().Add(-time.Second)))
+
+	// --- Then ---
+	assert.ErrorIs(t, <-done, os.ErrDeadlineExceeded)
+}
+
+func Test_Pipe_ReadContext(t *testing.T) {
+	// --- Given ---
+	p := NewPipe()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// --- When ---
+	n, err := p.ReadContext(ctx, make([]byte, 1))
+
+	// --- Then ---
+	assert.Exactly(t, context.DeadlineExceeded, err)
+	assert.Exactly(t, 0, n)
+}
+
+func Test_Pipe_Capacity(t *testing.T) {
+	// --- Given ---
+	p := NewPipe(Capacity(10))
+	data := testData(1000)
+
+	done := make(chan error)
+	go func() {
+		_, err := p.Write(data)
+		if err == nil {
+			err = p.CloseWrite()
+		}
+		done <- err
+	}()
+
+	// --- When ---
+	var got []byte
+	tmp := make([]byte, 7)
+	for {
+		assert.True(t, p.Len() <= 10)
+		n, err := p.Read(tmp)
+		got = append(got, tmp[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	// --- Then ---
+	assert.NoError(t, <-done)
+	assert.Exactly(t, data, got)
+}
+
+func Test_Pipe_WriteDeadline(t *testing.T) {
+	// --- Given ---
+	p := NewPipe(Capacity(2))
+	require.NoError(t, p.SetWriteDeadline(time.Now().Add(20*time.Millisecond)))
+
+	// --- When ---
+	n, err := p.Write([]byte{0, 1, 2})
+
+	// --- Then ---
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, 2, p.Len())
+}
+
+func Test_Pipe_WriteContext(t *testing.T) {
+	// --- Given ---
+	p := NewPipe(Capacity(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// --- When ---
+	n, err := p.WriteContext(ctx, []byte{0, 1, 2})
+
+	// --- Then ---
+	assert.Exactly(t, context.Canceled, err)
+	assert.Exactly(t, 2, n)
+}
+
+func Test_Pipe_Compact(t *testing.T) {
+	// --- Given ---
+	p := NewPipe(Capacity(64))
+	_, _ = p.Write(testData(64))
+	_, _ = p.Read(make([]byte, 60))
+
+	// --- When ---
+	_, err := p.Write(testData(60))
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 64, p.Len())
+	assert.Exactly(t, 0, p.buf.off)
+	assert.Exactly(t, 64, cap(p.buf.buf))
+}
@@ -0,0 +1,171 @@
+package flexbuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrUnset is returned by Finish and Close when some placeholders returned
+// by Reserve were not set.
+var ErrUnset = errors.New("placeholder not set")
+
+// ErrStalePlaceholder is returned when setting the placeholder which is no
+// longer part of the buffer because the buffer was closed, released,
+// truncated or discarded.
+var ErrStalePlaceholder = errors.New("stale placeholder")
+
+// ErrPlaceholderSize is returned when the value doesn't match the size of
+// the placeholder.
+var ErrPlaceholderSize = errors.New("invalid placeholder size")
+
+// Placeholder is the space reserved in the Buffer for the value which is
+// known only after more data is written, like length or checksum.
+type Placeholder struct {
+	// Buffer with the placeholder.
+	buf *Buffer
+	// Absolute offset of the placeholder.
+	off int64
+	// Placeholder size.
+	n int
+	// True when the placeholder was set.
+	set bool
+	// True when the placeholder is no longer part of the buffer.
+	stale bool
+}
+
+// Reserve writes n zero bytes at the current offset and returns the
+// Placeholder for them to be set later. The placeholders which are not
+// set are reported by Finish and Close. The buffer keeps track of the
+// placeholder until it's closed or released or the placeholder is
+// truncated or discarded, then the placeholder becomes stale and it's no
+// longer reported. If n is negative, Reserve will panic.
+func (b *Buffer) Reserve(n int) *Placeholder {
+	if n < 0 {
+		panic("flexbuf.Buffer.Reserve: negative count")
+	}
+
+	b.lock()
+	defer b.unlock()
+	ph := &Placeholder{
+		buf: b,
+		off: b.base + int64(b.off),
+		n:   n,
+	}
+	zeroOutSlice(b.slot(n))
+	if b.phs == nil {
+		b.phs = make(map[*Placeholder]struct{})
+	}
+	b.phs[ph] = struct{}{}
+	b.unset++
+	return ph
+}
+
+// Finish returns ErrUnset when any placeholder returned by Reserve
+// was not set.
+func (b *Buffer) Finish() error {
+	b.lock()
+	defer b.unlock()
+	return b.finish()
+}
+
+// invalidate marks placeholders not entirely within the range of absolute
+// offsets from lo to hi as stale. It must be called with the buffer locked.
+func (b *Buffer) invalidate(lo, hi int64) {
+	for ph := range b.phs {
+		if ph.off < lo || ph.off+int64(ph.n) > hi {
+			ph.stale = true
+			if !ph.set {
+				b.unset--
+			}
+			delete(b.phs, ph)
+		}
+	}
+}
+
+// finish returns ErrUnset when any placeholder was not set.
+func (b *Buffer) finish() error {
+	if b.unset > 0 {
+		return fmt.Errorf("%w: %d placeholders", ErrUnset, b.unset)
+	}
+	return nil
+}
+
+// Offset returns the absolute offset of the placeholder.
+func (ph *Placeholder) Offset() int64 {
+	return ph.off
+}
+
+// Len returns the placeholder size.
+func (ph *Placeholder) Len() int {
+	return ph.n
+}
+
+// Set writes p to the placeholder. It may be called many times. It
+// returns ErrPlaceholderSize when length of p is not equal to the
+// placeholder size and ErrStalePlaceholder when the buffer was closed or
+// released or the placeholder was truncated or discarded, even partially.
+func (ph *Placeholder) Set(p []byte) error {
+	if len(p) != ph.n {
+		return ErrPlaceholderSize
+	}
+
+	b := ph.buf
+	b.lock()
+	defer b.unlock()
+	if ph.stale {
+		return ErrStalePlaceholder
+	}
+	dst, err := b.slotAt(ph.off, ph.n)
+	if err != nil {
+		return err
+	}
+	copy(dst, p)
+	if !ph.set {
+		ph.set = true
+		b.unset--
+	}
+	return nil
+}
+
+// SetUint32 writes v to four bytes long placeholder using given byte
+// order.
+func (ph *Placeholder) SetUint32(order binary.ByteOrder, v uint32) error {
+	if ph.n != 4 {
+		return ErrPlaceholderSize
+	}
+	var tmp [4]byte
+	order.PutUint32(tmp[:], v)
+	return ph.Set(tmp[:])
+}
+
+// SetLengthSince writes the number of bytes between the end of the
+// placeholder and the current buffer offset using given byte order. The
+// placeholder must be 1, 2, 4 or 8 bytes long. It returns ErrTooLong
+// when the length doesn't fit in the placeholder and ErrOutOfBounds when
+// the current offset is before the end of the placeholder.
+func (ph *Placeholder) SetLengthSince(order binary.ByteOrder) error {
+	b := ph.buf
+	l := b.base + int64(b.off) - ph.off - int64(ph.n)
+	if l < 0 {
+		return ErrOutOfBounds
+	}
+
+	var tmp [8]byte
+	switch ph.n {
+	case 1:
+		tmp[0] = byte(l)
+	case 2:
+		order.PutUint16(tmp[:], uint16(l))
+	case 4:
+		order.PutUint32(tmp[:], uint32(l))
+	case 8:
+		order.PutUint64(tmp[:], uint64(l))
+	default:
+		return ErrPlaceholderSize
+	}
+	if ph.n < 8 && l>>(8*uint(ph.n)) != 0 {
+		return ErrTooLong
+	}
+	return ph.Set(tmp[:ph.n])
+}
@@ -0,0 +1,176 @@
+package flexbuf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Buffer_Reserve(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0xff, 0xff, 0xff, 0xff}, Offset(1))
+
+	// --- When ---
+	ph := buf.Reserve(2)
+
+	// --- Then ---
+	assert.Exactly(t, int64(1), ph.Offset())
+	assert.Exactly(t, 2, ph.Len())
+	assert.Exactly(t, 3, buf.Offset())
+	assert.Exactly(t, []byte{0xff, 0, 0, 0xff}, buf.buf)
+	assert.True(t, errors.Is(buf.Finish(), ErrUnset))
+
+	require.NoError(t, ph.Set([]byte{1, 2}))
+	assert.Exactly(t, []byte{0xff, 1, 2, 0xff}, buf.buf)
+	assert.Exactly(t, 3, buf.Offset())
+	assert.NoError(t, buf.Finish())
+
+	// Setting again doesn't change the count.
+	require.NoError(t, ph.Set([]byte{3, 4}))
+	assert.NoError(t, buf.Finish())
+	assert.Exactly(t, ErrPlaceholderSize, ph.Set([]byte{1}))
+}
+
+func Test_Placeholder_SetUint32(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	ph := buf.Reserve(4)
+	_, _ = buf.Write([]byte{9})
+
+	// --- When ---
+	err := ph.SetUint32(binary.BigEndian, 0x01020304)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{1, 2, 3, 4, 9}, buf.buf)
+	assert.Exactly(t, ErrPlaceholderSize, buf.Reserve(2).SetUint32(binary.BigEndian, 1))
+}
+
+func Test_Placeholder_SetLengthSince(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		n    int
+		data int
+		exp  []byte
+		err  error
+	}{
+		{"1 byte", 1, 3, []byte{3}, nil},
+		{"2 bytes", 2, 3, []byte{0, 3}, nil},
+		{"4 bytes", 4, 3, []byte{0, 0, 0, 3}, nil},
+		{"8 bytes", 8, 258, []byte{0, 0, 0, 0, 0, 0, 1, 2}, nil},
+		{"empty", 4, 0, []byte{0, 0, 0, 0}, nil},
+		{"overflow", 1, 256, []byte{0}, ErrTooLong},
+		{"invalid size", 3, 1, []byte{0, 0, 0}, ErrPlaceholderSize},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := &Buffer{}
+			ph := buf.Reserve(tc.n)
+			_, _ = buf.Write(make([]byte, tc.data))
+
+			// --- When ---
+			err := ph.SetLengthSince(binary.BigEndian)
+
+			// --- Then ---
+			assert.Exactly(t, tc.err, err)
+			assert.Exactly(t, tc.exp, buf.buf[:tc.n])
+		})
+	}
+}
+
+func Test_Placeholder_SetLengthSince_BeforeEnd(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	ph := buf.Reserve(4)
+	buf.SeekStart()
+
+	// --- When ---
+	err := ph.SetLengthSince(binary.LittleEndian)
+
+	// --- Then ---
+	assert.Exactly(t, ErrOutOfBounds, err)
+}
+
+func Test_Buffer_Close_Unset(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	buf.Reserve(4)
+	buf.Reserve(2)
+
+	// --- When ---
+	err := buf.Close()
+
+	// --- Then ---
+	assert.True(t, errors.Is(err, ErrUnset))
+	assert.Exactly(t, "placeholder not set: 2 placeholders", err.Error())
+	assert.Exactly(t, 0, buf.Len())
+	assert.NoError(t, buf.Close())
+}
+
+func Test_Placeholder_Set_Stale(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		fn func(buf *Buffer)
+	}{
+		{"close", func(buf *Buffer) { _ = buf.Close() }},
+		{"release", func(buf *Buffer) { _ = buf.Release() }},
+		{"truncate", func(buf *Buffer) { _ = buf.Truncate(3) }},
+		{"truncate and write", func(buf *Buffer) {
+			_ = buf.Truncate(0)
+			_, _ = buf.WriteAt(bytes.Repeat([]byte{0xff}, 13), 0)
+		}},
+		{"discard", func(buf *Buffer) { _ = buf.Discard(3) }},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With([]byte{1, 0})
+			buf.SeekEnd()
+			ph := buf.Reserve(2)
+			tc.fn(buf)
+			size := buf.Size()
+
+			// --- When ---
+			err := ph.Set([]byte{1, 2})
+
+			// --- Then ---
+			assert.Exactly(t, ErrStalePlaceholder, err)
+			assert.Exactly(t, size, buf.Size())
+			assert.NotContains(t, buf.buf, byte(2))
+			assert.NoError(t, buf.Finish())
+
+			// Stale placeholders don't hide new ones.
+			buf.Reserve(1)
+			assert.True(t, errors.Is(buf.Finish(), ErrUnset))
+		})
+	}
+}
+
+func Test_Placeholder_Set_TruncateAfter(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	ph := buf.Reserve(2)
+	_, _ = buf.Write([]byte{1, 2, 3})
+	require.NoError(t, buf.Truncate(2))
+
+	// --- When ---
+	err := ph.Set([]byte{4, 5})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{4, 5}, buf.buf)
+	assert.NoError(t, buf.Finish())
+}
+
+func Test_Buffer_Reserve_Negative(t *testing.T) {
+	assert.Panics(t, func() { (&Buffer{}).Reserve(-1) })
+}
@@ -0,0 +1,116 @@
+package flexbuf
+
+import (
+	"sort"
+)
+
+// Range represents a range of bytes.
+type Range struct {
+	// Offset of the first byte in the range.
+	Off int64
+	// Number of bytes in the range.
+	Len int64
+}
+
+// End returns the offset of the first byte after the range.
+func (r Range) End() int64 {
+	return r.Off + r.Len
+}
+
+// ranges is a set of byte ranges. The ranges are sorted by offset and
+// never overlap nor touch each other.
+type ranges []Range
+
+// add adds range r to the set merging it with overlapping or adjacent ones.
+func (rs *ranges) add(r Range) {
+	if r.Len <= 0 {
+		return
+	}
+
+	s := *rs
+	// First range which ends at or after r starts.
+	i := sort.Search(len(s), func(i int) bool { return s[i].End() >= r.Off })
+	// First range which starts after r ends.
+	j := i
+	for j < len(s) && s[j].Off <= r.End() {
+		j++
+	}
+
+	if i < j {
+		if s[i].Off < r.Off {
+			r.Len += r.Off - s[i].Off
+			r.Off = s[i].Off
+		}
+		if end := s[j-1].End(); end > r.End() {
+			r.Len = end - r.Off
+		}
+	}
+
+	// Replace ranges s[i:j] with r.
+	switch {
+	case i == j:
+		s = append(s, Range{})
+		copy(s[i+1:], s[i:])
+	case j-i > 1:
+		s = append(s[:i+1], s[j:]...)
+	}
+	s[i] = r
+	*rs = s
+}
+
+// cut removes bytes at offsets greater or equal to size from the set.
+func (rs *ranges) cut(size int64) {
+	s := *rs
+	i := sort.Search(len(s), func(i int) bool { return s[i].End() > size })
+	if i < len(s) && s[i].Off < size {
+		s[i].Len = size - s[i].Off
+		i++
+	}
+	*rs = s[:i]
+}
+
+// size returns the total number of bytes in the set.
+func (rs ranges) size() int64 {
+	var n int64
+	for _, r := range rs {
+		n += r.Len
+	}
+	return n
+}
+
+// contains returns true if all bytes of range r are in the set.
+func (rs ranges) contains(r Range) bool {
+	if r.Len <= 0 {
+		return true
+	}
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].End() > r.Off })
+	return i < len(rs) && rs[i].Off <= r.Off && rs[i].End() >= r.End()
+}
+
+// missing returns byte ranges between zero and size not in the set.
+func (rs ranges) missing(size int64) []Range {
+	var gaps []Range
+	var pos int64
+	for _, r := range rs {
+		if r.Off >= size {
+			break
+		}
+		if r.Off > pos {
+			gaps = append(gaps, Range{Off: pos, Len: r.Off - pos})
+		}
+		pos = r.End()
+	}
+	if pos < size {
+		gaps = append(gaps, Range{Off: pos, Len: size - pos})
+	}
+	return gaps
+}
+
+// prefix returns the number of consecutive bytes in the set starting
+// at offset zero.
+func (rs ranges) prefix() int64 {
+	if len(rs) == 0 || rs[0].Off > 0 {
+		return 0
+	}
+	return rs[0].Len
+}
@@ -0,0 +1,123 @@
+package flexbuf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ranges_add(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		init ranges
+		add  Range
+		exp  ranges
+	}{
+		{"empty", nil, Range{2, 3}, ranges{{2, 3}}},
+		{"zero length", ranges{{2, 3}}, Range{10, 0}, ranges{{2, 3}}},
+		{"before", ranges{{5, 3}}, Range{0, 2}, ranges{{0, 2}, {5, 3}}},
+		{"after", ranges{{0, 2}}, Range{5, 3}, ranges{{0, 2}, {5, 3}}},
+		{"between", ranges{{0, 2}, {10, 2}}, Range{5, 1}, ranges{{0, 2}, {5, 1}, {10, 2}}},
+		{"adjacent before", ranges{{5, 3}}, Range{2, 3}, ranges{{2, 6}}},
+		{"adjacent after", ranges{{5, 3}}, Range{8, 2}, ranges{{5, 5}}},
+		{"overlap start", ranges{{5, 3}}, Range{4, 2}, ranges{{4, 4}}},
+		{"overlap end", ranges{{5, 3}}, Range{7, 3}, ranges{{5, 5}}},
+		{"inside", ranges{{5, 10}}, Range{7, 2}, ranges{{5, 10}}},
+		{"cover", ranges{{5, 3}}, Range{0, 20}, ranges{{0, 20}}},
+		{"merge many", ranges{{0, 2}, {4, 2}, {8, 2}, {20, 1}}, Range{1, 8}, ranges{{0, 10}, {20, 1}}},
+		{"join two", ranges{{0, 2}, {4, 2}}, Range{2, 2}, ranges{{0, 6}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			rs := append(ranges(nil), tc.init...)
+
+			// --- When ---
+			rs.add(tc.add)
+
+			// --- Then ---
+			assert.Exactly(t, tc.exp, rs)
+		})
+	}
+}
+
+func Test_ranges_cut(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		init ranges
+		size int64
+		exp  ranges
+	}{
+		{"empty", nil, 10, ranges{}},
+		{"all", ranges{{2, 3}}, 0, ranges{}},
+		{"none", ranges{{2, 3}}, 5, ranges{{2, 3}}},
+		{"middle", ranges{{2, 3}, {10, 5}}, 12, ranges{{2, 3}, {10, 2}}},
+		{"between", ranges{{2, 3}, {10, 5}}, 7, ranges{{2, 3}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			rs := append(ranges{}, tc.init...)
+
+			// --- When ---
+			rs.cut(tc.size)
+
+			// --- Then ---
+			assert.Exactly(t, tc.exp, rs)
+		})
+	}
+}
+
+func Test_ranges_size(t *testing.T) {
+	// --- Given ---
+	rs := ranges{{0, 2}, {4, 3}}
+
+	// --- Then ---
+	assert.Exactly(t, int64(5), rs.size())
+}
+
+func Test_ranges_contains(t *testing.T) {
+	// --- Given ---
+	rs := ranges{{2, 3}, {10, 5}}
+
+	// --- Then ---
+	assert.True(t, rs.contains(Range{2, 3}))
+	assert.True(t, rs.contains(Range{3, 1}))
+	assert.True(t, rs.contains(Range{0, 0}))
+	assert.True(t, rs.contains(Range{11, 4}))
+	assert.False(t, rs.contains(Range{1, 2}))
+	assert.False(t, rs.contains(Range{4, 7}))
+	assert.False(t, rs.contains(Range{14, 2}))
+	assert.False(t, rs.contains(Range{20, 1}))
+}
+
+func Test_ranges_missing(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		init ranges
+		size int64
+		exp  []Range
+	}{
+		{"empty", nil, 10, []Range{{0, 10}}},
+		{"complete", ranges{{0, 10}}, 10, nil},
+		{"gaps", ranges{{2, 3}, {7, 1}}, 10, []Range{{0, 2}, {5, 2}, {8, 2}}},
+		{"beyond size", ranges{{0, 2}, {12, 1}}, 10, []Range{{2, 8}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			assert.Exactly(t, tc.exp, tc.init.missing(tc.size))
+		})
+	}
+}
+
+func Test_ranges_prefix(t *testing.T) {
+	assert.Exactly(t, int64(0), ranges(nil).prefix())
+	assert.Exactly(t, int64(0), ranges{{1, 3}}.prefix())
+	assert.Exactly(t, int64(3), ranges{{0, 3}, {5, 1}}.prefix())
+}
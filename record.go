@@ -0,0 +1,223 @@
+package flexbuf
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrMisaligned is returned by RecordView when the buffer length is not
+// a multiple of the record size.
+var ErrMisaligned = errors.New("buffer length not a multiple of record size")
+
+// ErrRecordSize is returned when written record length is not equal to
+// the record size.
+var ErrRecordSize = errors.New("invalid record length")
+
+// RecordView provides access to the array of fixed size records stored in
+// the Buffer starting at its first not discarded byte. It doesn't change
+// the buffer offset. Methods return ErrOutOfBounds when the record index
+// is out of range and ErrMisaligned when the buffer length is not
+// a multiple of the record size.
+type RecordView struct {
+	// Buffer with records.
+	buf *Buffer
+	// Record size.
+	size int
+}
+
+// NewRecordView returns new RecordView over records of size bytes in buf.
+// It panics with ErrOutOfBounds if size is not positive.
+func NewRecordView(buf *Buffer, size int) *RecordView {
+	if size <= 0 {
+		panic(ErrOutOfBounds)
+	}
+	return &RecordView{buf: buf, size: size}
+}
+
+// Size returns the record size.
+func (v *RecordView) Size() int {
+	return v.size
+}
+
+// Count returns the number of records. When the buffer length is not
+// a multiple of the record size it returns the number of complete records
+// and ErrMisaligned.
+func (v *RecordView) Count() (int, error) {
+	l := v.buf.Len()
+	if l%v.size != 0 {
+		return l / v.size, ErrMisaligned
+	}
+	return l / v.size, nil
+}
+
+// ReadRecord reads the record at index i to p. It returns
+// io.ErrShortBuffer when p is shorter than the record size.
+func (v *RecordView) ReadRecord(i int, p []byte) error {
+	if len(p) < v.size {
+		return io.ErrShortBuffer
+	}
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+	copy(p, v.buf.buf[j:j+v.size])
+	return nil
+}
+
+// WriteRecord replaces the record at index i with p. It returns
+// ErrRecordSize when the length of p is not equal to the record size.
+func (v *RecordView) WriteRecord(i int, p []byte) error {
+	if len(p) != v.size {
+		return ErrRecordSize
+	}
+
+	v.buf.lock()
+	defer v.buf.unlock()
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+	v.buf.own()
+	copy(v.buf.buf[j:], p)
+	return nil
+}
+
+// AppendRecord appends record p after the last record. It returns
+// ErrRecordSize when the length of p is not equal to the record size.
+func (v *RecordView) AppendRecord(p []byte) error {
+	if len(p) != v.size {
+		return ErrRecordSize
+	}
+
+	b := v.buf
+	b.lock()
+	defer b.unlock()
+	if _, err := v.Count(); err != nil {
+		return err
+	}
+	dst, err := b.slotAt(b.Size(), v.size)
+	if err != nil {
+		return err
+	}
+	copy(dst, p)
+	return nil
+}
+
+// DeleteRecord removes the record at index i moving all the following
+// records one position back so the order of records is kept.
+func (v *RecordView) DeleteRecord(i int) error {
+	b := v.buf
+	b.lock()
+	defer b.unlock()
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+	b.own()
+	copy(b.buf[j:], b.buf[j+v.size:])
+	return b.truncate(b.Size() - int64(v.size))
+}
+
+// SwapDeleteRecord removes the record at index i replacing it with the
+// last record. It's faster than DeleteRecord but doesn't keep the order
+// of records.
+func (v *RecordView) SwapDeleteRecord(i int) error {
+	b := v.buf
+	b.lock()
+	defer b.unlock()
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+	b.own()
+	copy(b.buf[j:j+v.size], b.buf[len(b.buf)-v.size:])
+	return b.truncate(b.Size() - int64(v.size))
+}
+
+// Truncate changes the number of records to n. New records are zeroed.
+// It returns os.ErrInvalid when n is negative.
+func (v *RecordView) Truncate(n int) error {
+	if n < 0 {
+		return os.ErrInvalid
+	}
+
+	b := v.buf
+	b.lock()
+	defer b.unlock()
+	if _, err := v.Count(); err != nil {
+		return err
+	}
+	return b.truncate(b.Base() + int64(n)*int64(v.size))
+}
+
+// index returns the index in the underlying buffer of the record i.
+func (v *RecordView) index(i int) (int, error) {
+	n, err := v.Count()
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i >= n {
+		return 0, ErrOutOfBounds
+	}
+	return v.buf.low + i*v.size, nil
+}
+
+// Records returns iterator over records starting at the first record.
+func (v *RecordView) Records() *RecordIter {
+	return &RecordIter{
+		v:   v,
+		i:   -1,
+		rec: make([]byte, v.size),
+	}
+}
+
+// RecordIter iterates over records in index order. Each record is copied
+// so it stays valid when the buffer is modified during the iteration.
+// The number of records is checked on every step so records appended
+// during the iteration are visited and removing records never causes
+// reading beyond the last record.
+type RecordIter struct {
+	v *RecordView
+	// Index of the current record.
+	i int
+	// Current record.
+	rec []byte
+	// Iteration error.
+	err error
+}
+
+// Next advances the iterator to the next record. It returns false when
+// there are no more records or an error occurred.
+func (it *RecordIter) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	err := it.v.ReadRecord(it.i+1, it.rec)
+	if err == ErrOutOfBounds {
+		return false
+	}
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.i++
+	return true
+}
+
+// Index returns the index of the current record.
+func (it *RecordIter) Index() int {
+	return it.i
+}
+
+// Record returns the current record. The returned slice is reused by
+// the next call to Next.
+func (it *RecordIter) Record() []byte {
+	return it.rec
+}
+
+// Err returns the error which stopped the iteration.
+func (it *RecordIter) Err() error {
+	return it.err
+}
@@ -0,0 +1,176 @@
+package flexbuf
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewRecordView_Panics(t *testing.T) {
+	assert.PanicsWithValue(t, ErrOutOfBounds, func() { NewRecordView(&Buffer{}, 0) })
+}
+
+func Test_RecordView_AppendRecord(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	v := NewRecordView(buf, 2)
+
+	// --- When ---
+	require.NoError(t, v.AppendRecord([]byte{0, 1}))
+	require.NoError(t, v.AppendRecord([]byte{2, 3}))
+
+	// --- Then ---
+	n, err := v.Count()
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, buf.buf)
+	assert.Exactly(t, 0, buf.Offset())
+	assert.Exactly(t, ErrRecordSize, v.AppendRecord([]byte{1}))
+}
+
+func Test_RecordView_ReadWriteRecord(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5})
+	v := NewRecordView(buf, 2)
+	p := make([]byte, 2)
+
+	// --- When ---
+	err := v.WriteRecord(1, []byte{9, 9})
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.NoError(t, v.ReadRecord(1, p))
+	assert.Exactly(t, []byte{9, 9}, p)
+	assert.NoError(t, v.ReadRecord(2, p))
+	assert.Exactly(t, []byte{4, 5}, p)
+
+	assert.Exactly(t, ErrOutOfBounds, v.ReadRecord(3, p))
+	assert.Exactly(t, ErrOutOfBounds, v.ReadRecord(-1, p))
+	assert.Exactly(t, io.ErrShortBuffer, v.ReadRecord(0, p[:1]))
+	assert.Exactly(t, ErrOutOfBounds, v.WriteRecord(3, p))
+	assert.Exactly(t, ErrRecordSize, v.WriteRecord(0, []byte{1, 2, 3}))
+}
+
+func Test_RecordView_DeleteRecord(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		swap bool
+		i    int
+		exp  []byte
+	}{
+		{"shift first", false, 0, []byte{2, 3, 4, 5, 6, 7}},
+		{"shift middle", false, 1, []byte{0, 1, 4, 5, 6, 7}},
+		{"shift last", false, 3, []byte{0, 1, 2, 3, 4, 5}},
+		{"swap first", true, 0, []byte{6, 7, 2, 3, 4, 5}},
+		{"swap middle", true, 1, []byte{0, 1, 6, 7, 4, 5}},
+		{"swap last", true, 3, []byte{0, 1, 2, 3, 4, 5}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+			v := NewRecordView(buf, 2)
+
+			// --- When ---
+			var err error
+			if tc.swap {
+				err = v.SwapDeleteRecord(tc.i)
+			} else {
+				err = v.DeleteRecord(tc.i)
+			}
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.exp, buf.buf)
+			assert.Exactly(t, byte(0), buf.buf[:8][7])
+		})
+	}
+}
+
+func Test_RecordView_Truncate(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5})
+	v := NewRecordView(buf, 2)
+
+	// --- When ---
+	require.NoError(t, v.Truncate(1))
+
+	// --- Then ---
+	assert.Exactly(t, []byte{0, 1}, buf.buf)
+	require.NoError(t, v.Truncate(2))
+	assert.Exactly(t, []byte{0, 1, 0, 0}, buf.buf)
+	assert.Exactly(t, os.ErrInvalid, v.Truncate(-1))
+}
+
+func Test_RecordView_Misaligned(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2})
+	v := NewRecordView(buf, 2)
+	p := make([]byte, 2)
+
+	// --- When ---
+	n, err := v.Count()
+
+	// --- Then ---
+	assert.Exactly(t, ErrMisaligned, err)
+	assert.Exactly(t, 1, n)
+	assert.Exactly(t, ErrMisaligned, v.ReadRecord(0, p))
+	assert.Exactly(t, ErrMisaligned, v.WriteRecord(0, p))
+	assert.Exactly(t, ErrMisaligned, v.AppendRecord(p))
+	assert.Exactly(t, ErrMisaligned, v.DeleteRecord(0))
+	assert.Exactly(t, ErrMisaligned, v.SwapDeleteRecord(0))
+	assert.Exactly(t, ErrMisaligned, v.Truncate(0))
+
+	it := v.Records()
+	assert.False(t, it.Next())
+	assert.Exactly(t, ErrMisaligned, it.Err())
+}
+
+func Test_RecordView_Discarded(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	require.NoError(t, buf.Discard(2))
+	v := NewRecordView(buf, 2)
+	p := make([]byte, 2)
+
+	// --- When ---
+	n, err := v.Count()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 3, n)
+	assert.NoError(t, v.ReadRecord(0, p))
+	assert.Exactly(t, []byte{2, 3}, p)
+	require.NoError(t, v.Truncate(1))
+	assert.Exactly(t, int64(4), buf.Size())
+}
+
+func Test_RecordIter(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5})
+	v := NewRecordView(buf, 2)
+
+	// --- When ---
+	var got [][]byte
+	var idx []int
+	it := v.Records()
+	for it.Next() {
+		got = append(got, append([]byte(nil), it.Record()...))
+		idx = append(idx, it.Index())
+		if it.Index() == 0 {
+			// Modifications during iteration are safe.
+			require.NoError(t, v.SwapDeleteRecord(2))
+			require.NoError(t, v.AppendRecord([]byte{6, 7}))
+		}
+	}
+
+	// --- Then ---
+	assert.NoError(t, it.Err())
+	assert.Exactly(t, []int{0, 1, 2}, idx)
+	assert.Exactly(t, [][]byte{{0, 1}, {2, 3}, {6, 7}}, got)
+}
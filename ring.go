@@ -0,0 +1,272 @@
+package flexbuf
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+)
+
+// ErrFull is returned when writing to the full ring buffer.
+var ErrFull = errors.New("ring buffer full")
+
+// Overwrite is the Ring constructor option making writes to the full ring
+// buffer overwrite the oldest data instead of returning ErrFull.
+func Overwrite(r *Ring) {
+	r.overwrite = true
+}
+
+// Ring is a ring buffer of fixed capacity. Its storage is allocated once
+// and never grows. By default writes which don't fit in the free space
+// are rejected with ErrFull, use Overwrite option to discard the oldest
+// data instead. Ring is not safe for concurrent use, see SPSCRing for
+// lock-free single producer single consumer ring buffer.
+type Ring struct {
+	// Storage, its length is the ring capacity.
+	buf Buffer
+	// Index of the first byte to read.
+	r int
+	// Number of buffered bytes.
+	n int
+	// Overwrite the oldest data when full.
+	overwrite bool
+}
+
+// NewRing returns new Ring with capacity of size bytes. It panics with
+// ErrOutOfBounds if size is not positive.
+func NewRing(size int, opts ...func(*Ring)) *Ring {
+	if size <= 0 {
+		panic(ErrOutOfBounds)
+	}
+
+	r := &Ring{}
+	_ = r.buf.Truncate(int64(size))
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Write writes len(p) bytes to the ring buffer. If the data doesn't fit
+// in the free space nothing is written and ErrFull is returned unless
+// Overwrite option was used in which case the oldest data is discarded
+// to make room. When p is bigger than the ring capacity only its last
+// bytes are kept.
+func (r *Ring) Write(p []byte) (int, error) {
+	size := len(r.buf.buf)
+	if len(p) > size-r.n {
+		if !r.overwrite {
+			return 0, ErrFull
+		}
+		if len(p) >= size {
+			copy(r.buf.buf, p[len(p)-size:])
+			r.r = 0
+			r.n = size
+			return len(p), nil
+		}
+		r.discard(len(p) - (size - r.n))
+	}
+
+	w := (r.r + r.n) % size
+	m := copy(r.buf.buf[w:], p)
+	copy(r.buf.buf, p[m:])
+	r.n += len(p)
+	return len(p), nil
+}
+
+// WriteByte writes single byte c to the ring buffer. See Write for
+// handling of the full ring buffer.
+func (r *Ring) WriteByte(c byte) error {
+	_, err := r.Write([]byte{c})
+	return err
+}
+
+// Read reads up to len(p) bytes from the ring buffer. If the ring buffer
+// has no data to return, err is io.EOF (unless len(p) is zero);
+// otherwise it is nil.
+func (r *Ring) Read(p []byte) (int, error) {
+	n, err := r.Peek(p)
+	r.discard(n)
+	return n, err
+}
+
+// ReadByte reads and returns the next byte from the ring buffer. It
+// returns io.EOF if the ring buffer is empty.
+func (r *Ring) ReadByte() (byte, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	c := r.buf.buf[r.r]
+	r.discard(1)
+	return c, nil
+}
+
+// Peek copies up to len(p) bytes from the ring buffer to p without
+// consuming them. If the ring buffer has no data to return, err is
+// io.EOF (unless len(p) is zero); otherwise it is nil.
+func (r *Ring) Peek(p []byte) (int, error) {
+	if len(p) > 0 && r.n == 0 {
+		return 0, io.EOF
+	}
+
+	a, b := r.segments()
+	n := copy(p, a)
+	n += copy(p[n:], b)
+	return n, nil
+}
+
+// Discard skips the next n bytes returning the number of bytes
+// discarded. If Discard skips fewer than n bytes, it returns io.EOF.
+// It returns os.ErrInvalid when n is negative.
+func (r *Ring) Discard(n int) (int, error) {
+	if n < 0 {
+		return 0, os.ErrInvalid
+	}
+	if n > r.n {
+		n = r.n
+		r.discard(n)
+		return n, io.EOF
+	}
+	r.discard(n)
+	return n, nil
+}
+
+// WriteTo writes buffered data to w using vectored write when the data
+// wraps around the end of the storage and w supports it (see
+// net.Buffers). The return value n is the number of bytes written.
+// Any error encountered during the write is also returned.
+func (r *Ring) WriteTo(w io.Writer) (int64, error) {
+	a, b := r.segments()
+	bufs := net.Buffers{a}
+	if len(b) > 0 {
+		bufs = append(bufs, b)
+	}
+	n, err := bufs.WriteTo(w)
+	r.discard(int(n))
+	return n, err
+}
+
+// segments returns buffered data as two slices of the storage.
+func (r *Ring) segments() ([]byte, []byte) {
+	end := r.r + r.n
+	if size := len(r.buf.buf); end > size {
+		return r.buf.buf[r.r:], r.buf.buf[:end-size]
+	}
+	return r.buf.buf[r.r:end], nil
+}
+
+// discard discards n buffered bytes.
+func (r *Ring) discard(n int) {
+	r.n -= n
+	if r.n == 0 {
+		r.r = 0
+		return
+	}
+	r.r = (r.r + n) % len(r.buf.buf)
+}
+
+// Len returns the number of buffered bytes.
+func (r *Ring) Len() int {
+	return r.n
+}
+
+// Cap returns the ring buffer capacity.
+func (r *Ring) Cap() int {
+	return len(r.buf.buf)
+}
+
+// Free returns the number of bytes which can be written without
+// overwriting or rejecting data.
+func (r *Ring) Free() int {
+	return len(r.buf.buf) - r.n
+}
+
+// Reset discards all buffered data.
+func (r *Ring) Reset() {
+	r.r = 0
+	r.n = 0
+}
+
+// SPSCRing is a lock-free ring buffer of fixed capacity safe for use by
+// one writing and one reading goroutine at the same time. Writes never
+// overwrite unread data.
+type SPSCRing struct {
+	// Total number of bytes read, accessed atomically.
+	head uint64
+	// Keep head and tail in separate cache lines.
+	_ [56]byte
+	// Total number of bytes written, accessed atomically.
+	tail uint64
+	_    [56]byte
+	// Storage, its length is the ring capacity.
+	buf Buffer
+}
+
+// NewSPSCRing returns new SPSCRing with capacity of size bytes. It panics
+// with ErrOutOfBounds if size is not positive.
+func NewSPSCRing(size int) *SPSCRing {
+	if size <= 0 {
+		panic(ErrOutOfBounds)
+	}
+	r := &SPSCRing{}
+	_ = r.buf.Truncate(int64(size))
+	return r
+}
+
+// Write writes as many bytes from p as fit in the free space. It returns
+// the number of bytes written and ErrFull if it's less than len(p).
+// It must be called only by the producer goroutine.
+func (r *SPSCRing) Write(p []byte) (int, error) {
+	head := atomic.LoadUint64(&r.head)
+	tail := r.tail // Only the producer changes tail.
+	size := uint64(len(r.buf.buf))
+
+	var err error
+	n := len(p)
+	if free := size - (tail - head); uint64(n) > free {
+		n = int(free)
+		err = ErrFull
+	}
+
+	w := int(tail % size)
+	m := copy(r.buf.buf[w:], p[:n])
+	copy(r.buf.buf, p[m:n])
+	atomic.StoreUint64(&r.tail, tail+uint64(n))
+	return n, err
+}
+
+// Read reads up to len(p) bytes from the ring buffer. If the ring buffer
+// has no data to return, err is io.EOF (unless len(p) is zero);
+// otherwise it is nil. It must be called only by the consumer goroutine.
+func (r *SPSCRing) Read(p []byte) (int, error) {
+	head := r.head // Only the consumer changes head.
+	tail := atomic.LoadUint64(&r.tail)
+	size := uint64(len(r.buf.buf))
+
+	if len(p) > 0 && head == tail {
+		return 0, io.EOF
+	}
+
+	n := len(p)
+	if avail := tail - head; uint64(n) > avail {
+		n = int(avail)
+	}
+
+	i := int(head % size)
+	m := copy(p[:n], r.buf.buf[i:])
+	copy(p[m:n], r.buf.buf)
+	atomic.StoreUint64(&r.head, head+uint64(n))
+	return n, nil
+}
+
+// Len returns the number of buffered bytes.
+func (r *SPSCRing) Len() int {
+	head := atomic.LoadUint64(&r.head)
+	return int(atomic.LoadUint64(&r.tail) - head)
+}
+
+// Cap returns the ring buffer capacity.
+func (r *SPSCRing) Cap() int {
+	return len(r.buf.buf)
+}
@@ -0,0 +1,307 @@
+package flexbuf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ringWith returns ring buffer of capacity size with read index moved
+// to off and data written to it.
+func ringWith(size, off int, data []byte, opts ...func(*Ring)) *Ring {
+	r := NewRing(size, opts...)
+	r.r = off
+	_, _ = r.Write(data)
+	return r
+}
+
+func Test_NewRing(t *testing.T) {
+	// --- When ---
+	r := NewRing(8)
+
+	// --- Then ---
+	assert.Exactly(t, 8, r.Cap())
+	assert.Exactly(t, 0, r.Len())
+	assert.Exactly(t, 8, r.Free())
+}
+
+func Test_NewRing_Panics(t *testing.T) {
+	assert.PanicsWithValue(t, ErrOutOfBounds, func() { NewRing(0) })
+}
+
+func Test_Ring_Write(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		off  int
+		data []byte
+		exp  []byte
+		len  int
+	}{
+		{"empty", 0, []byte{}, []byte{0, 0, 0, 0}, 0},
+		{"no wrap", 0, []byte{1, 2, 3}, []byte{1, 2, 3, 0}, 3},
+		{"wrap", 2, []byte{1, 2, 3}, []byte{3, 0, 1, 2}, 3},
+		{"full", 3, []byte{1, 2, 3, 4}, []byte{2, 3, 4, 1}, 4},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			r := NewRing(4)
+			r.r = tc.off
+
+			// --- When ---
+			n, err := r.Write(tc.data)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, len(tc.data), n)
+			assert.Exactly(t, tc.exp, r.buf.buf)
+			assert.Exactly(t, tc.len, r.Len())
+		})
+	}
+}
+
+func Test_Ring_Write_Full(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 0, []byte{1, 2, 3})
+
+	// --- When ---
+	n, err := r.Write([]byte{4, 5})
+
+	// --- Then ---
+	assert.ErrorIs(t, err, ErrFull)
+	assert.Exactly(t, 0, n)
+	assert.Exactly(t, 3, r.Len())
+	assert.NoError(t, r.WriteByte(4))
+	assert.ErrorIs(t, r.WriteByte(5), ErrFull)
+}
+
+func Test_Ring_Write_Overwrite(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		data []byte
+		exp  []byte
+	}{
+		{"one byte", []byte{4, 5}, []byte{2, 3, 4, 5}},
+		{"all", []byte{4, 5, 6, 7}, []byte{4, 5, 6, 7}},
+		{"bigger than capacity", []byte{4, 5, 6, 7, 8, 9}, []byte{6, 7, 8, 9}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			r := ringWith(4, 2, []byte{1, 2, 3}, Overwrite)
+
+			// --- When ---
+			n, err := r.Write(tc.data)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, len(tc.data), n)
+			got := make([]byte, 8)
+			n, _ = r.Read(got)
+			assert.Exactly(t, tc.exp, got[:n])
+		})
+	}
+}
+
+func Test_Ring_Read(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 3, []byte{1, 2, 3})
+
+	// --- When ---
+	got := make([]byte, 2)
+	n, err := r.Read(got)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, []byte{1, 2}, got)
+	assert.Exactly(t, 1, r.Len())
+
+	c, err := r.ReadByte()
+	assert.NoError(t, err)
+	assert.Exactly(t, byte(3), c)
+
+	_, err = r.ReadByte()
+	assert.Exactly(t, io.EOF, err)
+	n, err = r.Read(got)
+	assert.Exactly(t, io.EOF, err)
+	assert.Exactly(t, 0, n)
+	n, err = r.Read(nil)
+	assert.NoError(t, err)
+	assert.Exactly(t, 0, n)
+}
+
+func Test_Ring_Peek(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 2, []byte{1, 2, 3})
+
+	// --- When ---
+	got := make([]byte, 4)
+	n, err := r.Peek(got)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{1, 2, 3}, got[:n])
+	assert.Exactly(t, 3, r.Len())
+
+	_, err = NewRing(4).Peek(got)
+	assert.Exactly(t, io.EOF, err)
+}
+
+func Test_Ring_Discard(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 2, []byte{1, 2, 3})
+
+	// --- When ---
+	n, err := r.Discard(2)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	c, _ := r.ReadByte()
+	assert.Exactly(t, byte(3), c)
+
+	_, _ = r.Write([]byte{4})
+	n, err = r.Discard(2)
+	assert.Exactly(t, io.EOF, err)
+	assert.Exactly(t, 1, n)
+}
+
+func Test_Ring_Discard_Negative(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 2, []byte{1, 2, 3})
+
+	// --- When ---
+	n, err := r.Discard(-1)
+
+	// --- Then ---
+	assert.Exactly(t, os.ErrInvalid, err)
+	assert.Exactly(t, 0, n)
+	assert.Exactly(t, 3, r.Len())
+	c, _ := r.ReadByte()
+	assert.Exactly(t, byte(1), c)
+}
+
+func Test_Ring_WriteTo(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 2, []byte{1, 2, 3})
+	dst := &bytes.Buffer{}
+
+	// --- When ---
+	n, err := r.WriteTo(dst)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(3), n)
+	assert.Exactly(t, []byte{1, 2, 3}, dst.Bytes())
+	assert.Exactly(t, 0, r.Len())
+}
+
+func Test_Ring_WriteTo_Error(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 2, []byte{1, 2, 3})
+	dst := &errWriter{n: 1}
+
+	// --- When ---
+	n, err := r.WriteTo(dst)
+
+	// --- Then ---
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+	assert.Exactly(t, int64(1), n)
+	assert.Exactly(t, 2, r.Len())
+}
+
+func Test_Ring_Reset(t *testing.T) {
+	// --- Given ---
+	r := ringWith(4, 2, []byte{1, 2, 3})
+
+	// --- When ---
+	r.Reset()
+
+	// --- Then ---
+	assert.Exactly(t, 0, r.Len())
+	assert.Exactly(t, 4, r.Free())
+}
+
+func Test_SPSCRing(t *testing.T) {
+	// --- Given ---
+	r := NewSPSCRing(64)
+	data := testData(10000)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p := data
+		for len(p) > 0 {
+			m := 50
+			if m > len(p) {
+				m = len(p)
+			}
+			n, _ := r.Write(p[:m])
+			p = p[n:]
+			if n == 0 {
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	// --- When ---
+	got := make([]byte, 0, len(data))
+	tmp := make([]byte, 37)
+	for len(got) < len(data) {
+		n, _ := r.Read(tmp)
+		got = append(got, tmp[:n]...)
+		if n == 0 {
+			runtime.Gosched()
+		}
+	}
+	<-done
+
+	// --- Then ---
+	assert.Exactly(t, data, got)
+	assert.Exactly(t, 0, r.Len())
+}
+
+func Test_SPSCRing_Full(t *testing.T) {
+	// --- Given ---
+	r := NewSPSCRing(4)
+	_, _ = r.Write([]byte{1, 2, 3})
+	_, _ = r.Read(make([]byte, 2))
+
+	// --- When ---
+	n, err := r.Write([]byte{4, 5, 6, 7})
+
+	// --- Then ---
+	assert.ErrorIs(t, err, ErrFull)
+	assert.Exactly(t, 3, n)
+	assert.Exactly(t, 4, r.Len())
+	assert.Exactly(t, 4, r.Cap())
+
+	got := make([]byte, 8)
+	n, err = r.Read(got)
+	require.NoError(t, err)
+	assert.Exactly(t, []byte{3, 4, 5, 6}, got[:n])
+	_, err = r.Read(got)
+	assert.Exactly(t, io.EOF, err)
+}
+
+// errWriter writes at most n bytes and returns io.ErrShortWrite.
+type errWriter struct {
+	n int
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return w.n, io.ErrShortWrite
+	}
+	return len(p), nil
+}
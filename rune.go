@@ -0,0 +1,77 @@
+package flexbuf
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrUnread is returned by UnreadRune and UnreadByte when there is
+// nothing to unread.
+var ErrUnread = errors.New("previous operation was not a successful read")
+
+// ReadRune reads a single UTF-8 encoded rune at the current offset and
+// advances the offset. It returns the rune and its size in bytes. When
+// the bytes at the current offset are not a valid UTF-8 encoding, for
+// example the offset is in the middle of a multi-byte sequence or the
+// buffer ends before the sequence does, it consumes one byte and returns
+// utf8.RuneError with size 1. It returns io.EOF when there is nothing
+// to read.
+func (b *Buffer) ReadRune() (rune, int, error) {
+	p, err := b.peek(b.off, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r, n := rune(p[0]), 1
+	if r >= utf8.RuneSelf {
+		r, n = utf8.DecodeRune(b.buf[b.off:])
+	}
+	b.off += n
+	b.runeEnd = b.base + int64(b.off)
+	b.runeLen = n
+	return r, n, nil
+}
+
+// UnreadRune unreads the rune returned by the last ReadRune call moving
+// the offset back by its size. It returns ErrUnread when the offset was
+// changed since the last ReadRune call.
+func (b *Buffer) UnreadRune() error {
+	if b.runeLen == 0 || b.runeEnd != b.base+int64(b.off) {
+		return ErrUnread
+	}
+	i, err := b.rel(b.runeEnd - int64(b.runeLen))
+	if err != nil {
+		return ErrUnread
+	}
+	b.off = i
+	b.runeLen = 0
+	return nil
+}
+
+// UnreadByte moves the offset back by one byte. It returns ErrUnread
+// when the offset is at the beginning of the buffer or the previous byte
+// was discarded.
+func (b *Buffer) UnreadByte() error {
+	if b.off <= b.low {
+		return ErrUnread
+	}
+	b.off--
+	b.runeLen = 0
+	return nil
+}
+
+// WriteRune writes the UTF-8 encoding of r at the current offset and
+// advances the offset. Invalid runes are written as utf8.RuneError. It
+// returns the number of bytes written, the error is always nil.
+func (b *Buffer) WriteRune(r rune) (int, error) {
+	b.lock()
+	defer b.unlock()
+
+	if r >= 0 && r < utf8.RuneSelf {
+		b.write([]byte{byte(r)})
+		return 1, nil
+	}
+	var tmp [utf8.UTFMax]byte
+	n := utf8.EncodeRune(tmp[:], r)
+	return b.write(tmp[:n]), nil
+}
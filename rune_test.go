@@ -0,0 +1,181 @@
+package flexbuf
+
+import (
+	"io"
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Interface guards.
+var (
+	_ io.ByteScanner = &Buffer{}
+	_ io.RuneScanner = &Buffer{}
+)
+
+func Test_Buffer_ReadRune(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		data []byte
+		off  int
+		exp  rune
+		n    int
+		err  error
+	}{
+		{"ascii", []byte("a€"), 0, 'a', 1, nil},
+		{"multi byte", []byte("a€"), 1, '€', 3, nil},
+		{"middle of sequence", []byte("a€"), 2, utf8.RuneError, 1, nil},
+		{"truncated sequence", []byte("a€")[:3], 1, utf8.RuneError, 1, nil},
+		{"invalid byte", []byte{0xff}, 0, utf8.RuneError, 1, nil},
+		{"encoded rune error", []byte("�"), 0, utf8.RuneError, 3, nil},
+		{"eof", []byte("a€"), 4, 0, 0, io.EOF},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With(tc.data, Offset(tc.off))
+
+			// --- When ---
+			r, n, err := buf.ReadRune()
+
+			// --- Then ---
+			assert.Exactly(t, tc.err, err)
+			assert.Exactly(t, tc.exp, r)
+			assert.Exactly(t, tc.n, n)
+			assert.Exactly(t, tc.off+tc.n, buf.Offset())
+		})
+	}
+}
+
+func Test_Buffer_UnreadRune(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte("a€b"))
+	_, _, _ = buf.ReadRune()
+	_, _, _ = buf.ReadRune()
+
+	// --- When ---
+	err := buf.UnreadRune()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 1, buf.Offset())
+	assert.Exactly(t, ErrUnread, buf.UnreadRune())
+
+	r, _, _ := buf.ReadRune()
+	assert.Exactly(t, '€', r)
+}
+
+func Test_Buffer_UnreadRune_Errors(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		fn func(buf *Buffer)
+	}{
+		{"no read", func(buf *Buffer) {}},
+		{"after read byte", func(buf *Buffer) { _, _ = buf.ReadByte() }},
+		{"after seek", func(buf *Buffer) {
+			_, _, _ = buf.ReadRune()
+			_, _ = buf.Seek(0, io.SeekStart)
+		}},
+		{"after write", func(buf *Buffer) {
+			_, _, _ = buf.ReadRune()
+			_, _ = buf.Write([]byte{'x'})
+		}},
+		{"after unread byte", func(buf *Buffer) {
+			_, _, _ = buf.ReadRune()
+			_ = buf.UnreadByte()
+		}},
+		{"after discard", func(buf *Buffer) {
+			_, _, _ = buf.ReadRune()
+			_ = buf.Discard(3)
+		}},
+		{"after close", func(buf *Buffer) {
+			_, _, _ = buf.ReadRune()
+			_ = buf.Close()
+			_, _ = buf.Write([]byte("€"))
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With([]byte("€ab"))
+			tc.fn(buf)
+			off := buf.Offset()
+
+			// --- When ---
+			err := buf.UnreadRune()
+
+			// --- Then ---
+			assert.Exactly(t, ErrUnread, err)
+			assert.Exactly(t, off, buf.Offset())
+		})
+	}
+}
+
+func Test_Buffer_UnreadByte(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte("abc"))
+	_, _ = buf.ReadByte()
+	_, _ = buf.ReadByte()
+
+	// --- When ---
+	err := buf.UnreadByte()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 1, buf.Offset())
+	b, _ := buf.ReadByte()
+	assert.Exactly(t, byte('b'), b)
+
+	require.NoError(t, buf.Discard(2))
+	assert.Exactly(t, ErrUnread, buf.UnreadByte())
+	assert.Exactly(t, ErrUnread, With(nil).UnreadByte())
+}
+
+func Test_Buffer_WriteRune(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		r   rune
+		exp []byte
+	}{
+		{"ascii", 'a', []byte("a")},
+		{"multi byte", '€', []byte("€")},
+		{"four bytes", '😀', []byte("😀")},
+		{"invalid", -1, []byte("�")},
+		{"surrogate", 0xD800, []byte("�")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With([]byte("xxxxx"), Offset(1))
+
+			// --- When ---
+			n, err := buf.WriteRune(tc.r)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, len(tc.exp), n)
+			assert.Exactly(t, 1+n, buf.Offset())
+			assert.Exactly(t, tc.exp, buf.buf[1:1+n])
+		})
+	}
+}
+
+func Test_Buffer_RuneReader_Regexp(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte("zażółć gęślą jaźń"))
+
+	// --- When ---
+	loc := regexp.MustCompile(`gęś+lą`).FindReaderIndex(buf)
+
+	// --- Then ---
+	assert.Exactly(t, []int{11, 19}, loc)
+}
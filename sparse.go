@@ -0,0 +1,212 @@
+package flexbuf
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// Whence values for the Sparse.Seek method, they have the same values and
+// meaning as SEEK_DATA and SEEK_HOLE on Linux, see lseek(2) for details.
+const (
+	// SeekData seeks to the next data at or after the offset.
+	SeekData = 3
+	// SeekHole seeks to the next hole at or after the offset.
+	SeekHole = 4
+)
+
+// ErrNoExtent is returned by Sparse.Seek when there is no data or hole at
+// or after the offset. It corresponds to ENXIO error returned by lseek(2).
+var ErrNoExtent = errors.New("no data or hole at or after offset")
+
+// Sparse is a sparse buffer. Only written data is stored in memory, regions
+// which were never written are holes which cost no memory and read as
+// zeros or as the content produced by the generator set with Synthetic
+// option. Extending the buffer with Truncate or by writing beyond its
+// end creates holes.
+type Sparse struct {
+	// Buffer size.
+	size int64
+	// Current offset for read and write operations.
+	off int64
+	// Written data.
+	ext extents
+	// Holes content generator, nil for zeros.
+	gen Generator
+}
+
+// NewSparse returns new Sparse buffer of given size consisting of a single
+// hole.
+func NewSparse(size int64, opts ...func(*Sparse)) *Sparse {
+	s := &Sparse{size: size}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Write writes the contents of p to the buffer at current offset. The
+// return value n is the length of p; err is always nil.
+func (s *Sparse) Write(p []byte) (int, error) {
+	n, err := s.WriteAt(p, s.off)
+	s.off += int64(n)
+	return n, err
+}
+
+// WriteAt writes len(p) bytes to the buffer starting at byte offset off,
+// growing the buffer as needed. It returns the number of bytes written;
+// err is os.ErrInvalid when off is negative. It does not change the offset.
+func (s *Sparse) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	s.ext.write(p, off)
+	if end := off + int64(len(p)); end > s.size {
+		s.size = end
+	}
+	return len(p), nil
+}
+
+// Read reads the next len(p) bytes from the buffer or until the buffer
+// is drained. The return value is the number of bytes read. If the
+// buffer has no data to return, err is io.EOF (unless len(p) is zero).
+func (s *Sparse) Read(p []byte) (int, error) {
+	if len(p) > 0 && s.off >= s.size {
+		return 0, io.EOF
+	}
+	n, err := s.ReadAt(p, s.off)
+	s.off += int64(n)
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from the buffer starting at byte offset off.
+// It returns the number of bytes read and the error, if any. ReadAt
+// always returns a non-nil error when n < len(p). It does not change
+// the offset.
+func (s *Sparse) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	if off >= s.size {
+		return 0, io.EOF
+	}
+
+	want := p
+	if rem := s.size - off; int64(len(want)) > rem {
+		want = want[:rem]
+	}
+
+	if err := s.ext.read(want, off, s.hole); err != nil {
+		return 0, err
+	}
+
+	if len(want) < len(p) {
+		return len(want), io.EOF
+	}
+	return len(want), nil
+}
+
+// hole fills p with bytes of the hole at offset off.
+func (s *Sparse) hole(p []byte, off int64) error {
+	if s.gen != nil {
+		s.gen(p, off)
+		return nil
+	}
+	zeroOutSlice(p)
+	return nil
+}
+
+// Seek sets the offset for the next Read or Write on the buffer to offset,
+// interpreted according to whence: 0 means relative to the origin of the
+// file, 1 means relative to the current offset, 2 means relative to the
+// end, SeekData means the next data at or after offset and SeekHole means
+// the next hole at or after offset. There is an implicit hole at the end
+// of the buffer. It returns the new offset and an error: os.ErrInvalid
+// if calculated offset < 0 or ErrNoExtent if there is no data or hole
+// at or after offset.
+func (s *Sparse) Seek(offset int64, whence int) (int64, error) {
+	var off int64
+	switch whence {
+	case io.SeekStart:
+		off = offset
+	case io.SeekCurrent:
+		off = s.off + offset
+	case io.SeekEnd:
+		off = s.size + offset
+
+	case SeekData:
+		if offset < 0 {
+			return 0, os.ErrInvalid
+		}
+		if offset >= s.size {
+			return 0, ErrNoExtent
+		}
+		var ok bool
+		if off, ok = s.ext.data(offset); !ok {
+			return 0, ErrNoExtent
+		}
+
+	case SeekHole:
+		if offset < 0 {
+			return 0, os.ErrInvalid
+		}
+		if offset >= s.size {
+			return 0, ErrNoExtent
+		}
+		off = s.ext.hole(offset)
+	}
+
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	s.off = off
+
+	return s.off, nil
+}
+
+// Truncate changes the size of the buffer discarding bytes at offsets
+// greater then size. Extending the buffer creates a hole. It does not
+// change the offset. It returns error os.ErrInvalid only when when size
+// is negative.
+func (s *Sparse) Truncate(size int64) error {
+	if size < 0 {
+		return os.ErrInvalid
+	}
+	if size < s.size {
+		s.ext.cut(size)
+	}
+	s.size = size
+	return nil
+}
+
+// Offset returns the current offset.
+func (s *Sparse) Offset() int64 {
+	return s.off
+}
+
+// Size returns the buffer size including holes.
+func (s *Sparse) Size() int64 {
+	return s.size
+}
+
+// Allocated returns the number of bytes stored in memory.
+func (s *Sparse) Allocated() int64 {
+	return s.ext.size()
+}
+
+// Extents returns the byte ranges holding data in offset order.
+func (s *Sparse) Extents() []Range {
+	return s.ext.ranges()
+}
+
+// Close releases the memory, sets the buffer size and the offset to zero.
+// It always returns nil error.
+func (s *Sparse) Close() error {
+	s.off = 0
+	s.size = 0
+	s.ext = nil
+	return nil
+}
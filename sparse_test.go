@@ -0,0 +1,171 @@
+package flexbuf
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Sparse_Truncate_Huge(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(0)
+
+	// --- When ---
+	err := s.Truncate(10 << 30)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(10<<30), s.Size())
+	assert.Exactly(t, int64(0), s.Allocated())
+
+	got := []byte{1, 2, 3}
+	n, err := s.ReadAt(got, 5<<30)
+	assert.NoError(t, err)
+	assert.Exactly(t, 3, n)
+	assert.Exactly(t, []byte{0, 0, 0}, got)
+}
+
+func Test_Sparse_WriteAt_Huge(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(0)
+
+	// --- When ---
+	n, err := s.WriteAt([]byte{1, 2}, 10<<30)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, int64(10<<30+2), s.Size())
+	assert.Exactly(t, int64(2), s.Allocated())
+	assert.Exactly(t, []Range{{10 << 30, 2}}, s.Extents())
+}
+
+func Test_Sparse_Read(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(8)
+	_, err := s.WriteAt([]byte{1, 2}, 2)
+	require.NoError(t, err)
+	_, err = s.Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	_, err = s.Write([]byte{3, 4, 5})
+	require.NoError(t, err)
+	_, err = s.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	// --- When ---
+	got, err := ioutil.ReadAll(s)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 0, 1, 2, 0, 0, 3, 4, 5}, got)
+	assert.Exactly(t, []Range{{2, 2}, {6, 3}}, s.Extents())
+}
+
+func Test_Sparse_ReadAt_BeyondSize(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(4)
+
+	// --- When ---
+	n, err := s.ReadAt(make([]byte, 10), 2)
+
+	// --- Then ---
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Exactly(t, 2, n)
+}
+
+func Test_Sparse_Seek_DataHole(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		offset int64
+		whence int
+		exp    int64
+		expErr error
+	}{
+		{"data in hole", 0, SeekData, 4, nil},
+		{"data in data", 5, SeekData, 5, nil},
+		{"data in second hole", 7, SeekData, 10, nil},
+		{"data in last hole", 13, SeekData, 0, ErrNoExtent},
+		{"data at end", 16, SeekData, 0, ErrNoExtent},
+		{"hole in hole", 1, SeekHole, 1, nil},
+		{"hole in data", 4, SeekHole, 6, nil},
+		{"hole in last data", 11, SeekHole, 12, nil},
+		{"hole in last hole", 14, SeekHole, 14, nil},
+		{"hole at end", 16, SeekHole, 0, ErrNoExtent},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			s := NewSparse(16)
+			_, err := s.WriteAt([]byte{1, 2}, 4)
+			require.NoError(t, err)
+			_, err = s.WriteAt([]byte{3, 4}, 10)
+			require.NoError(t, err)
+
+			// --- When ---
+			off, err := s.Seek(tc.offset, tc.whence)
+
+			// --- Then ---
+			if tc.expErr != nil {
+				assert.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.exp, off)
+			assert.Exactly(t, tc.exp, s.Offset())
+		})
+	}
+}
+
+func Test_Sparse_Seek_HoleAtEnd(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(0)
+	_, err := s.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+
+	// --- When ---
+	off, err := s.Seek(0, SeekHole)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(3), off)
+}
+
+func Test_Sparse_Truncate(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(0)
+	_, err := s.Write([]byte{1, 2, 3, 4})
+	require.NoError(t, err)
+
+	// --- When ---
+	require.NoError(t, s.Truncate(2))
+	require.NoError(t, s.Truncate(4))
+
+	// --- Then ---
+	got := make([]byte, 4)
+	_, err = s.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{1, 2, 0, 0}, got)
+	assert.Exactly(t, int64(2), s.Allocated())
+	assert.Error(t, s.Truncate(-1))
+}
+
+func Test_Sparse_Close(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(0)
+	_, err := s.Write([]byte{1, 2, 3, 4})
+	require.NoError(t, err)
+
+	// --- When ---
+	err = s.Close()
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(0), s.Size())
+	assert.Exactly(t, int64(0), s.Offset())
+	assert.Exactly(t, int64(0), s.Allocated())
+}
@@ -0,0 +1,413 @@
+package flexbuf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidStruct is returned when the value can't be encoded or decoded
+// because of its type or struct tags.
+var ErrInvalidStruct = errors.New("invalid struct")
+
+// ErrTooLong is returned when the value doesn't fit in its field.
+var ErrTooLong = errors.New("value too long")
+
+// Cache of compiled codecs by codecKey.
+var codecs sync.Map
+
+// byteType is the type of byte.
+var byteType = reflect.TypeOf(byte(0))
+
+// codecKey identifies compiled codec.
+type codecKey struct {
+	typ reflect.Type
+	le  bool
+}
+
+// DecodeAt decodes the value pointed by v from the buffer at offset off
+// without changing the offset. The value may be a boolean, a fixed size
+// number, an array or a struct of those. Fields are laid out one after
+// another in big endian byte order unless struct tags say otherwise:
+//
+//	type Header struct {
+//	    Magic   [4]byte
+//	    Version uint16 `flexbuf:"le"`        // Little endian.
+//	    _       [2]byte                      // Padding.
+//	    Name    string `flexbuf:"size=16"`   // Fixed size string.
+//	    Size    uint64 `flexbuf:"off=32,be"` // Explicit offset.
+//	    Extra   Ext    `flexbuf:"le"`        // Default for Ext fields.
+//	    Ignored int    `flexbuf:"-"`
+//	}
+//
+// The off option sets the field offset relative to the beginning of the
+// struct, following fields are placed after it. Strings require size
+// option, they are padded with zero bytes when encoded and trailing zero
+// bytes are removed when decoded. Blank and unexported fields of any type
+// are padding, their size is the size option or the size of the Go type.
+// The layout of each type is computed once and cached.
+//
+// It returns io.EOF or io.ErrUnexpectedEOF when the buffer is too short
+// and ErrInvalidStruct when the type is not supported.
+func (b *Buffer) DecodeAt(off int64, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("%w: expected non-nil pointer, got %T", ErrInvalidStruct, v)
+	}
+	rv = rv.Elem()
+
+	c, err := codecOf(rv.Type(), false)
+	if err != nil {
+		return err
+	}
+	p, err := b.nextAt(off, c.size())
+	if err != nil {
+		return err
+	}
+	c.decode(p, rv)
+	return nil
+}
+
+// EncodeAt encodes v to the buffer at offset off without changing the
+// offset, growing the buffer as needed. Bytes not covered by any field
+// are zeroed. See DecodeAt for the layout rules. It returns ErrTooLong
+// when a string doesn't fit in its field, the buffer is not changed on
+// error.
+func (b *Buffer) EncodeAt(off int64, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return fmt.Errorf("%w: expected value, got %T", ErrInvalidStruct, v)
+	}
+
+	c, err := codecOf(rv.Type(), false)
+	if err != nil {
+		return err
+	}
+
+	// Encode to scratch slice first so the buffer is touched only when
+	// the whole value is encoded.
+	tmp := make([]byte, c.size())
+	if err := c.encode(tmp, rv); err != nil {
+		return err
+	}
+
+	b.lock()
+	defer b.unlock()
+	p, err := b.slotAt(off, len(tmp))
+	if err != nil {
+		return err
+	}
+	copy(p, tmp)
+	return nil
+}
+
+// codec encodes and decodes values of one type.
+type codec interface {
+	// size returns the encoded size in bytes.
+	size() int
+	// decode decodes p to v.
+	decode(p []byte, v reflect.Value)
+	// encode encodes v to p.
+	encode(p []byte, v reflect.Value) error
+}
+
+// codecOf returns cached codec for type t using little endian byte order
+// by default when le is true.
+func codecOf(t reflect.Type, le bool) (codec, error) {
+	key := codecKey{typ: t, le: le}
+	if c, ok := codecs.Load(key); ok {
+		return c.(codec), nil
+	}
+
+	c, err := compile(t, le)
+	if err != nil {
+		return nil, err
+	}
+	codecs.Store(key, c)
+	return c, nil
+}
+
+// compile returns codec for type t.
+func compile(t reflect.Type, le bool) (codec, error) {
+	var order binary.ByteOrder = binary.BigEndian
+	if le {
+		order = binary.LittleEndian
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolCodec{}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return numCodec{kind: t.Kind(), n: int(t.Size()), order: order}, nil
+	case reflect.Array:
+		elem, err := codecOf(t.Elem(), le)
+		if err != nil {
+			return nil, err
+		}
+		return arrayCodec{elem: elem, n: t.Len()}, nil
+	case reflect.Struct:
+		return compileStruct(t, le)
+	}
+	return nil, fmt.Errorf("%w: unsupported type %s", ErrInvalidStruct, t)
+}
+
+// compileStruct returns codec for struct type t.
+func compileStruct(t reflect.Type, le bool) (codec, error) {
+	sc := &structCodec{}
+
+	var off int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("flexbuf")
+		if tag == "-" {
+			continue
+		}
+
+		fle, size := le, -1
+		if tag != "" {
+			for _, opt := range strings.Split(tag, ",") {
+				var err error
+				switch {
+				case opt == "le":
+					fle = true
+				case opt == "be":
+					fle = false
+				case strings.HasPrefix(opt, "off="):
+					off, err = strconv.Atoi(opt[4:])
+				case strings.HasPrefix(opt, "size="):
+					size, err = strconv.Atoi(opt[5:])
+				default:
+					err = errors.New("unknown option")
+				}
+				if err != nil || off < 0 {
+					return nil, fmt.Errorf("%w: %s.%s tag option %q", ErrInvalidStruct, t, f.Name, opt)
+				}
+			}
+		}
+
+		pad := f.Name == "_" || f.PkgPath != ""
+
+		var c codec
+		var err error
+		switch {
+		case pad && size >= 0:
+			c = padCodec{n: size}
+		case pad:
+			c = padCodec{n: int(f.Type.Size())}
+		case f.Type.Kind() == reflect.String:
+			if size < 0 {
+				return nil, fmt.Errorf("%w: %s.%s string requires size", ErrInvalidStruct, t, f.Name)
+			}
+			c = stringCodec{n: size}
+		default:
+			c, err = codecOf(f.Type, fle)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		sc.fields = append(sc.fields, field{
+			index: i,
+			off:   off,
+			codec: c,
+			pad:   pad,
+		})
+		off += c.size()
+		if off > sc.n {
+			sc.n = off
+		}
+	}
+
+	return sc, nil
+}
+
+// field describes struct field.
+type field struct {
+	// Field index.
+	index int
+	// Offset of the field relative to the beginning of the struct.
+	off int
+	// Field codec.
+	codec codec
+	// Padding fields are not encoded nor decoded.
+	pad bool
+}
+
+// structCodec encodes structs.
+type structCodec struct {
+	fields []field
+	n      int
+}
+
+func (c *structCodec) size() int { return c.n }
+
+func (c *structCodec) decode(p []byte, v reflect.Value) {
+	for _, f := range c.fields {
+		if !f.pad {
+			f.codec.decode(p[f.off:], v.Field(f.index))
+		}
+	}
+}
+
+func (c *structCodec) encode(p []byte, v reflect.Value) error {
+	for _, f := range c.fields {
+		if f.pad {
+			continue
+		}
+		if err := f.codec.encode(p[f.off:], v.Field(f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// arrayCodec encodes arrays.
+type arrayCodec struct {
+	elem codec
+	n    int
+}
+
+func (c arrayCodec) size() int { return c.n * c.elem.size() }
+
+func (c arrayCodec) decode(p []byte, v reflect.Value) {
+	// Named byte types can't be copied from []byte.
+	if v.Type().Elem() == byteType {
+		reflect.Copy(v, reflect.ValueOf(p[:c.n]))
+		return
+	}
+	es := c.elem.size()
+	for i := 0; i < c.n; i++ {
+		c.elem.decode(p[i*es:], v.Index(i))
+	}
+}
+
+func (c arrayCodec) encode(p []byte, v reflect.Value) error {
+	es := c.elem.size()
+	for i := 0; i < c.n; i++ {
+		if err := c.elem.encode(p[i*es:], v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringCodec encodes fixed size strings.
+type stringCodec struct {
+	n int
+}
+
+func (c stringCodec) size() int { return c.n }
+
+func (c stringCodec) decode(p []byte, v reflect.Value) {
+	v.SetString(string(bytes.TrimRight(p[:c.n], "\x00")))
+}
+
+func (c stringCodec) encode(p []byte, v reflect.Value) error {
+	s := v.String()
+	if len(s) > c.n {
+		return fmt.Errorf("%w: string of %d bytes in %d bytes field", ErrTooLong, len(s), c.n)
+	}
+	copy(p, s)
+	return nil
+}
+
+// padCodec skips padding bytes.
+type padCodec struct {
+	n int
+}
+
+func (c padCodec) size() int { return c.n }
+
+func (padCodec) decode(p []byte, v reflect.Value) {}
+
+func (padCodec) encode(p []byte, v reflect.Value) error { return nil }
+
+// boolCodec encodes booleans as single byte.
+type boolCodec struct{}
+
+func (boolCodec) size() int { return 1 }
+
+func (boolCodec) decode(p []byte, v reflect.Value) {
+	v.SetBool(p[0] != 0)
+}
+
+func (boolCodec) encode(p []byte, v reflect.Value) error {
+	if v.Bool() {
+		p[0] = 1
+	}
+	return nil
+}
+
+// numCodec encodes fixed size numbers.
+type numCodec struct {
+	kind  reflect.Kind
+	n     int
+	order binary.ByteOrder
+}
+
+func (c numCodec) size() int { return c.n }
+
+func (c numCodec) decode(p []byte, v reflect.Value) {
+	var u uint64
+	switch c.n {
+	case 1:
+		u = uint64(p[0])
+	case 2:
+		u = uint64(c.order.Uint16(p))
+	case 4:
+		u = uint64(c.order.Uint32(p))
+	default:
+		u = c.order.Uint64(p)
+	}
+
+	switch c.kind {
+	case reflect.Int8:
+		v.SetInt(int64(int8(u)))
+	case reflect.Int16:
+		v.SetInt(int64(int16(u)))
+	case reflect.Int32:
+		v.SetInt(int64(int32(u)))
+	case reflect.Int64:
+		v.SetInt(int64(u))
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(uint32(u))))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(u))
+	default:
+		v.SetUint(u)
+	}
+}
+
+func (c numCodec) encode(p []byte, v reflect.Value) error {
+	var u uint64
+	switch c.kind {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		u = uint64(v.Int())
+	case reflect.Float32:
+		u = uint64(math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		u = math.Float64bits(v.Float())
+	default:
+		u = v.Uint()
+	}
+
+	switch c.n {
+	case 1:
+		p[0] = byte(u)
+	case 2:
+		c.order.PutUint16(p, uint16(u))
+	case 4:
+		c.order.PutUint32(p, uint32(u))
+	default:
+		c.order.PutUint64(p, u)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package flexbuf
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testExt struct {
+	A uint16
+	B int32 `flexbuf:"be"`
+}
+
+type testHeader struct {
+	Magic   [4]byte
+	Version uint16 `flexbuf:"le"`
+	_       [2]byte
+	Name    string `flexbuf:"size=8"`
+	Flag    bool
+	Temp    int8
+	Ratio   float32
+	Size    uint64  `flexbuf:"off=32"`
+	Ext     testExt `flexbuf:"le"`
+	Ignored int     `flexbuf:"-"`
+}
+
+func Test_Buffer_EncodeAt(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0xff, 0xff}, Offset(1))
+	h := testHeader{
+		Magic:   [4]byte{'F', 'L', 'E', 'X'},
+		Version: 0x0102,
+		Name:    "hdr",
+		Flag:    true,
+		Temp:    -2,
+		Ratio:   1,
+		Size:    0x0a0b,
+		Ext:     testExt{A: 0x0304, B: -1},
+		Ignored: 7,
+	}
+
+	// --- When ---
+	err := buf.EncodeAt(2, &h)
+
+	// --- Then ---
+	require.NoError(t, err)
+	exp := []byte{
+		0xff, 0xff,
+		'F', 'L', 'E', 'X',
+		0x02, 0x01,
+		0, 0,
+		'h', 'd', 'r', 0, 0, 0, 0, 0,
+		1,
+		0xfe,
+		0x3f, 0x80, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0x0a, 0x0b,
+		0x04, 0x03,
+		0xff, 0xff, 0xff, 0xff,
+	}
+	assert.Exactly(t, exp, buf.buf)
+	assert.Exactly(t, 1, buf.Offset())
+
+	var got testHeader
+	require.NoError(t, buf.DecodeAt(2, &got))
+	h.Ignored = 0
+	assert.Exactly(t, h, got)
+	assert.Exactly(t, 1, buf.Offset())
+}
+
+func Test_Buffer_EncodeAt_ZeroesGaps(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0xff, 0xff, 0xff, 0xff})
+	v := struct {
+		A uint8
+		_ [2]byte
+		B uint8
+	}{A: 1, B: 2}
+
+	// --- When ---
+	err := buf.EncodeAt(0, v)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{1, 0, 0, 2}, buf.buf)
+}
+
+func Test_Buffer_DecodeAt_Values(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0x01, 0x02, 0x03, 0x04})
+
+	// --- When ---
+	var u uint32
+	var a [2]uint16
+	err1 := buf.DecodeAt(0, &u)
+	err2 := buf.DecodeAt(0, &a)
+
+	// --- Then ---
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Exactly(t, uint32(0x01020304), u)
+	assert.Exactly(t, [2]uint16{0x0102, 0x0304}, a)
+}
+
+// testByte is named byte type.
+type testByte uint8
+
+func Test_Buffer_DecodeAt_NamedByteArray(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{1, 2, 3, 4})
+	var v struct{ X [4]testByte }
+
+	// --- When ---
+	err := buf.DecodeAt(0, &v)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, [4]testByte{1, 2, 3, 4}, v.X)
+}
+
+func Test_Buffer_DecodeAt_Errors(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		off int64
+		v   interface{}
+		err error
+	}{
+		{"eof", 4, new(uint16), io.EOF},
+		{"unexpected eof", 3, new(uint16), io.ErrUnexpectedEOF},
+		{"negative offset", -1, new(uint16), ErrOutOfBounds},
+		{"not pointer", 0, uint16(0), ErrInvalidStruct},
+		{"nil pointer", 0, (*uint16)(nil), ErrInvalidStruct},
+		{"unsupported", 0, new(int), ErrInvalidStruct},
+		{"no string size", 0, &struct{ S string }{}, ErrInvalidStruct},
+		{"bad option", 0, &struct {
+			A uint8 `flexbuf:"xx"`
+		}{}, ErrInvalidStruct},
+		{"bad offset", 0, &struct {
+			A uint8 `flexbuf:"off=-1"`
+		}{}, ErrInvalidStruct},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With([]byte{0, 1, 2, 3})
+
+			// --- When ---
+			err := buf.DecodeAt(tc.off, tc.v)
+
+			// --- Then ---
+			assert.True(t, errors.Is(err, tc.err), "%v", err)
+		})
+	}
+}
+
+func Test_Buffer_EncodeAt_TooLong(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	v := struct {
+		S string `flexbuf:"size=2"`
+	}{S: "abc"}
+
+	// --- When ---
+	err := buf.EncodeAt(0, v)
+
+	// --- Then ---
+	assert.True(t, errors.Is(err, ErrTooLong))
+	assert.Exactly(t, int64(0), buf.Size())
+}
+
+func Test_Buffer_EncodeAt_TooLong_Unchanged(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0xff, 0xff, 0xff, 0xff})
+	v := struct {
+		A uint16
+		S string `flexbuf:"size=2"`
+	}{A: 1, S: "abc"}
+
+	// --- When ---
+	err := buf.EncodeAt(0, v)
+
+	// --- Then ---
+	assert.True(t, errors.Is(err, ErrTooLong))
+	assert.Exactly(t, []byte{0xff, 0xff, 0xff, 0xff}, buf.buf)
+}
+
+func Test_Buffer_EncodeAt_UnsupportedPadding(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	v := struct {
+		A  uint8
+		mu sync.Mutex
+		s  string `flexbuf:"size=2"`
+		_  int32
+		B  uint8
+	}{A: 1, B: 2}
+	exp := make([]byte, 1+unsafe.Sizeof(v.mu)+2+4+1)
+	exp[0], exp[len(exp)-1] = 1, 2
+
+	// --- When ---
+	err := buf.EncodeAt(0, &v)
+
+	// --- Then ---
+	require.NoError(t, err)
+	assert.Exactly(t, exp, buf.buf)
+
+	var got struct {
+		A  uint8
+		mu sync.Mutex
+		s  string `flexbuf:"size=2"`
+		_  int32
+		B  uint8
+	}
+	require.NoError(t, buf.DecodeAt(0, &got))
+	assert.Exactly(t, uint8(1), got.A)
+	assert.Exactly(t, uint8(2), got.B)
+}
+
+func Test_Buffer_DecodeAt_Cached(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	require.NoError(t, buf.EncodeAt(0, testHeader{Name: "abc"}))
+	var h testHeader
+
+	// --- When ---
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = buf.DecodeAt(0, &h)
+	})
+
+	// --- Then ---
+	// Only the decoded string allocates.
+	assert.Exactly(t, float64(1), allocs)
+}
@@ -0,0 +1,66 @@
+package flexbuf
+
+// Generator fills p with synthetic content of the buffer starting at offset
+// off. The content must depend only on the offset so the same bytes are
+// produced regardless of how the buffer is read.
+type Generator func(p []byte, off int64)
+
+// Synthetic is the Sparse constructor option making holes read as content
+// produced by the generator g instead of zeros. Together with Truncate it
+// allows creating huge buffers with deterministic content without
+// allocating them, written data still overlays the generated content.
+func Synthetic(g Generator) func(*Sparse) {
+	return func(s *Sparse) {
+		s.gen = g
+	}
+}
+
+// GenPattern returns generator repeating the pattern. The pattern byte at
+// index off % len(pattern) is the byte at offset off.
+// It will panic with ErrOutOfBounds if the pattern is empty.
+func GenPattern(pattern []byte) Generator {
+	if len(pattern) == 0 {
+		panic(ErrOutOfBounds)
+	}
+	pat := append([]byte(nil), pattern...)
+	return func(p []byte, off int64) {
+		n := copy(p, pat[off%int64(len(pat)):])
+		for n < len(p) {
+			n += copy(p[n:], pat)
+		}
+	}
+}
+
+// GenCounter returns generator dividing the buffer into 8 byte words each
+// holding its index encoded as big endian uint64.
+func GenCounter() Generator {
+	return func(p []byte, off int64) {
+		for i := range p {
+			pos := off + int64(i)
+			p[i] = byte(uint64(pos/8) >> (56 - 8*uint(pos%8)))
+		}
+	}
+}
+
+// GenRandom returns generator producing pseudo-random bytes. The same seed
+// always produces the same content. The generator is not cryptographically
+// secure.
+func GenRandom(seed uint64) Generator {
+	return func(p []byte, off int64) {
+		var word uint64
+		for i := range p {
+			pos := off + int64(i)
+			if i == 0 || pos%8 == 0 {
+				word = splitmix64(seed + uint64(pos/8)*0x9E3779B97F4A7C15)
+			}
+			p[i] = byte(word >> (8 * uint(pos%8)))
+		}
+	}
+}
+
+// splitmix64 returns SplitMix64 mix of x.
+func splitmix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
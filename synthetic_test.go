@@ -0,0 +1,96 @@
+package flexbuf
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GenPattern(t *testing.T) {
+	// --- Given ---
+	g := GenPattern([]byte{1, 2, 3})
+
+	// --- When ---
+	got := make([]byte, 8)
+	g(got, 2)
+
+	// --- Then ---
+	assert.Exactly(t, []byte{3, 1, 2, 3, 1, 2, 3, 1}, got)
+}
+
+func Test_GenPattern_Empty(t *testing.T) {
+	assert.Panics(t, func() { GenPattern(nil) })
+}
+
+func Test_GenCounter(t *testing.T) {
+	// --- Given ---
+	g := GenCounter()
+
+	// --- When ---
+	got := make([]byte, 12)
+	g(got, 14)
+
+	// --- Then ---
+	assert.Exactly(t, []byte{0, 1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0}, got)
+}
+
+func Test_GenRandom_Deterministic(t *testing.T) {
+	// --- Given ---
+	g := GenRandom(42)
+	exp := make([]byte, 100)
+	g(exp, 0)
+
+	// --- When ---
+	got := make([]byte, 100)
+	for i := 0; i < 100; i += 7 {
+		end := i + 7
+		if end > 100 {
+			end = 100
+		}
+		g(got[i:end], int64(i))
+	}
+
+	// --- Then ---
+	assert.Exactly(t, exp, got)
+	other := make([]byte, 100)
+	GenRandom(43)(other, 0)
+	assert.NotEqual(t, exp, other)
+}
+
+func Test_Sparse_Synthetic(t *testing.T) {
+	// --- Given ---
+	s := NewSparse(10, Synthetic(GenPattern([]byte{7})))
+
+	// --- When ---
+	_, err := s.WriteAt([]byte{1, 2}, 3)
+	require.NoError(t, err)
+
+	// --- Then ---
+	got, err := ioutil.ReadAll(s)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{7, 7, 7, 1, 2, 7, 7, 7, 7, 7}, got)
+	assert.Exactly(t, int64(2), s.Allocated())
+}
+
+func Test_Sparse_Synthetic_Huge(t *testing.T) {
+	// --- Given ---
+	s1 := NewSparse(8<<30, Synthetic(GenRandom(1)))
+	s2 := NewSparse(8<<30, Synthetic(GenRandom(1)))
+
+	// --- When ---
+	got1 := make([]byte, 1000)
+	_, err := s1.ReadAt(got1, 4<<30)
+	require.NoError(t, err)
+
+	got2 := make([]byte, 1000)
+	_, err = s2.ReadAt(got2[:500], 4<<30)
+	require.NoError(t, err)
+	_, err = s2.ReadAt(got2[500:], 4<<30+500)
+	require.NoError(t, err)
+
+	// --- Then ---
+	assert.Exactly(t, got1, got2)
+	assert.Exactly(t, int64(0), s1.Allocated())
+}
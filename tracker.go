@@ -0,0 +1,178 @@
+package flexbuf
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// trackerMagic is the magic string starting the serialized Tracker state.
+const trackerMagic = "FLEXBUFT"
+
+// ErrInvalidState is returned when serialized Tracker state can't be decoded.
+var ErrInvalidState = errors.New("invalid tracker state")
+
+// Tracker tracks byte ranges written to the Buffer of known size, for
+// example when assembling it from chunks downloaded out of order. All
+// writes to the buffer should go through the tracker. Tracker is safe
+// for concurrent use by multiple goroutines.
+type Tracker struct {
+	// Guards all the fields and the buffer.
+	mu sync.Mutex
+	// Tracked buffer.
+	buf *Buffer
+	// Expected buffer size.
+	size int64
+	// Written byte ranges.
+	done ranges
+	// Closed and set to nil when new ranges are written.
+	notify chan struct{}
+}
+
+// NewTracker returns new Tracker for the buffer of expected size.
+func NewTracker(buf *Buffer, size int64) *Tracker {
+	return &Tracker{
+		buf:  buf,
+		size: size,
+	}
+}
+
+// WriteAt writes len(p) bytes to the buffer starting at byte offset off
+// and marks the range as written. It does not change the buffer offset.
+func (t *Tracker) WriteAt(p []byte, off int64) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n, err := t.buf.WriteAt(p, off)
+	t.done.add(Range{Off: off, Len: int64(n)})
+	if t.notify != nil && n > 0 {
+		close(t.notify)
+		t.notify = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from the buffer starting at byte offset off.
+// It can be used concurrently with WriteAt. It does not check if the
+// range was written, use Wait method for that.
+func (t *Tracker) ReadAt(p []byte, off int64) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.ReadAt(p, off)
+}
+
+// Size returns the expected buffer size.
+func (t *Tracker) Size() int64 {
+	return t.size
+}
+
+// Written returns written byte ranges.
+func (t *Tracker) Written() []Range {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Range(nil), t.done...)
+}
+
+// Missing returns byte ranges which were not written yet.
+func (t *Tracker) Missing() []Range {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done.missing(t.size)
+}
+
+// Prefix returns the length of the written data starting at offset zero
+// without any gaps.
+func (t *Tracker) Prefix() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done.prefix()
+}
+
+// Complete returns true when all bytes of the buffer were written.
+func (t *Tracker) Complete() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done.contains(Range{Off: 0, Len: t.size})
+}
+
+// Wait blocks until all n bytes starting at offset off are written or the
+// context is done in which case the context error is returned.
+func (t *Tracker) Wait(ctx context.Context, off, n int64) error {
+	for {
+		t.mu.Lock()
+		if t.done.contains(Range{Off: off, Len: n}) {
+			t.mu.Unlock()
+			return nil
+		}
+		if t.notify == nil {
+			t.notify = make(chan struct{})
+		}
+		ch := t.notify
+		t.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// MarshalBinary encodes the tracker state so it can be restored with
+// UnmarshalBinary, for example to resume the download after restart.
+// The buffer data is not part of the state.
+//
+// The state starts with 8 byte magic string "FLEXBUFT" followed by
+// big endian uint64 values: the expected size, the number of written
+// ranges and offset and length of each range.
+func (t *Tracker) MarshalBinary() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := make([]byte, len(trackerMagic)+16+16*len(t.done))
+	copy(data, trackerMagic)
+	binary.BigEndian.PutUint64(data[8:], uint64(t.size))
+	binary.BigEndian.PutUint64(data[16:], uint64(len(t.done)))
+	for i, r := range t.done {
+		binary.BigEndian.PutUint64(data[24+16*i:], uint64(r.Off))
+		binary.BigEndian.PutUint64(data[32+16*i:], uint64(r.Len))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary restores the tracker state encoded with MarshalBinary.
+// It returns ErrInvalidState if the state is malformed.
+func (t *Tracker) UnmarshalBinary(data []byte) error {
+	if len(data) < 24 || string(data[:8]) != trackerMagic {
+		return ErrInvalidState
+	}
+
+	size := int64(binary.BigEndian.Uint64(data[8:]))
+	cnt := binary.BigEndian.Uint64(data[16:])
+	if size < 0 || cnt != uint64(len(data)-24)/16 || len(data)%16 != 8 {
+		return ErrInvalidState
+	}
+
+	var done ranges
+	for i := 0; i < int(cnt); i++ {
+		r := Range{
+			Off: int64(binary.BigEndian.Uint64(data[24+16*i:])),
+			Len: int64(binary.BigEndian.Uint64(data[32+16*i:])),
+		}
+		if r.Off < 0 || r.Len < 0 {
+			return ErrInvalidState
+		}
+		done.add(r)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.size = size
+	t.done = done
+	if t.notify != nil {
+		close(t.notify)
+		t.notify = nil
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package flexbuf
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Tracker_WriteAt(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	require.NoError(t, buf.Truncate(10))
+	trk := NewTracker(buf, 10)
+
+	// --- When ---
+	_, err := trk.WriteAt([]byte{5, 6}, 5)
+	require.NoError(t, err)
+	_, err = trk.WriteAt([]byte{0, 1}, 0)
+	require.NoError(t, err)
+
+	// --- Then ---
+	assert.Exactly(t, []Range{{0, 2}, {5, 2}}, trk.Written())
+	assert.Exactly(t, []Range{{2, 3}, {7, 3}}, trk.Missing())
+	assert.Exactly(t, int64(2), trk.Prefix())
+	assert.False(t, trk.Complete())
+	assert.Exactly(t, []byte{0, 1, 0, 0, 0, 5, 6, 0, 0, 0}, buf.buf)
+}
+
+func Test_Tracker_Complete(t *testing.T) {
+	// --- Given ---
+	trk := NewTracker(&Buffer{}, 4)
+
+	// --- When ---
+	_, err := trk.WriteAt([]byte{2, 3}, 2)
+	require.NoError(t, err)
+	_, err = trk.WriteAt([]byte{0, 1}, 0)
+	require.NoError(t, err)
+
+	// --- Then ---
+	assert.True(t, trk.Complete())
+	assert.Nil(t, trk.Missing())
+	assert.Exactly(t, int64(4), trk.Prefix())
+
+	got := make([]byte, 4)
+	_, err = trk.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{0, 1, 2, 3}, got)
+}
+
+func Test_Tracker_Wait(t *testing.T) {
+	// --- Given ---
+	trk := NewTracker(&Buffer{}, 10)
+	done := make(chan error)
+
+	go func() {
+		done <- trk.Wait(context.Background(), 2, 4)
+	}()
+
+	// --- When ---
+	_, err := trk.WriteAt([]byte{2, 3}, 2)
+	require.NoError(t, err)
+	select {
+	case <-done:
+		t.Fatal("Wait returned before range was written")
+	case <-time.After(10 * time.Millisecond):
+	}
+	_, err = trk.WriteAt([]byte{4, 5}, 4)
+	require.NoError(t, err)
+
+	// --- Then ---
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return")
+	}
+}
+
+func Test_Tracker_Wait_Context(t *testing.T) {
+	// --- Given ---
+	trk := NewTracker(&Buffer{}, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// --- When ---
+	err := trk.Wait(ctx, 0, 1)
+
+	// --- Then ---
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_Tracker_Concurrent(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	require.NoError(t, buf.Truncate(1000))
+	trk := NewTracker(buf, 1000)
+	data := testData(1000)
+
+	// --- When ---
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			_, _ = trk.WriteAt(data[i*100:(i+1)*100], int64(i*100))
+		}(i)
+	}
+	err := trk.Wait(context.Background(), 0, 1000)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.True(t, trk.Complete())
+	assert.Exactly(t, data, buf.buf)
+}
+
+func Test_Tracker_MarshalBinary(t *testing.T) {
+	// --- Given ---
+	trk := NewTracker(&Buffer{}, 10)
+	_, err := trk.WriteAt([]byte{0, 1}, 0)
+	require.NoError(t, err)
+	_, err = trk.WriteAt([]byte{5}, 5)
+	require.NoError(t, err)
+
+	// --- When ---
+	state, err := trk.MarshalBinary()
+	require.NoError(t, err)
+
+	got := NewTracker(&Buffer{}, 0)
+	err = got.UnmarshalBinary(state)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(10), got.Size())
+	assert.Exactly(t, trk.Written(), got.Written())
+	assert.Exactly(t, trk.Missing(), got.Missing())
+}
+
+func Test_Tracker_UnmarshalBinary_Invalid(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		state []byte
+	}{
+		{"empty", nil},
+		{"magic", []byte("XXXXXXXX0000000000000000")},
+		{"count", append([]byte(trackerMagic), make([]byte, 15)...)},
+		{
+			"ranges",
+			append([]byte(trackerMagic), []byte{
+				0, 0, 0, 0, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, 0, 0, 2,
+				0, 0, 0, 0, 0, 0, 0, 0,
+				0, 0, 0, 0, 0, 0, 0, 1,
+			}...),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			trk := NewTracker(&Buffer{}, 0)
+
+			// --- When ---
+			err := trk.UnmarshalBinary(tc.state)
+
+			// --- Then ---
+			assert.ErrorIs(t, err, ErrInvalidState)
+		})
+	}
+}
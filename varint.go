@@ -0,0 +1,227 @@
+package flexbuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrOverflow is returned when decoded variable length integer doesn't fit
+// in 64 bits.
+var ErrOverflow = errors.New("varint overflows 64-bit integer")
+
+// Variable length integers are encoded as in encoding/binary package.
+// Uvarint encoding is the same as unsigned LEB128, Varint is the zig-zag
+// encoded signed integer stored as Uvarint. Signed LEB128 (used by WASM
+// and DWARF) stores two's complement integers and is handled by SLEB128
+// methods. Read methods return io.EOF when no bytes are available,
+// io.ErrUnexpectedEOF when the buffer ends in the middle of the value
+// and ErrOverflow when the value doesn't fit in 64 bits, in all cases the
+// offset is not changed. The ...At forms use absolute offsets, don't
+// change the offset and return the encoded length.
+
+// WriteUvarint writes v encoded as Uvarint at the current offset and
+// advances the offset. It returns the number of bytes written.
+func (b *Buffer) WriteUvarint(v uint64) (int, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return b.Write(tmp[:n])
+}
+
+// WriteUvarintAt writes v encoded as Uvarint at offset off. It returns
+// the number of bytes written.
+func (b *Buffer) WriteUvarintAt(off int64, v uint64) (int, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return b.WriteAt(tmp[:n], off)
+}
+
+// ReadUvarint reads Uvarint encoded integer at the current offset and
+// advances the offset.
+func (b *Buffer) ReadUvarint() (uint64, error) {
+	v, n, err := uvarint(b.from(b.off))
+	if err != nil {
+		return 0, err
+	}
+	b.off += n
+	return v, nil
+}
+
+// ReadUvarintAt reads Uvarint encoded integer at offset off. It returns
+// the value and its encoded length.
+func (b *Buffer) ReadUvarintAt(off int64) (uint64, int, error) {
+	i, err := b.rel(off)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uvarint(b.from(i))
+}
+
+// WriteVarint writes v encoded as Varint at the current offset and
+// advances the offset. It returns the number of bytes written.
+func (b *Buffer) WriteVarint(v int64) (int, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	return b.Write(tmp[:n])
+}
+
+// WriteVarintAt writes v encoded as Varint at offset off. It returns
+// the number of bytes written.
+func (b *Buffer) WriteVarintAt(off int64, v int64) (int, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	return b.WriteAt(tmp[:n], off)
+}
+
+// ReadVarint reads Varint encoded integer at the current offset and
+// advances the offset.
+func (b *Buffer) ReadVarint() (int64, error) {
+	u, n, err := uvarint(b.from(b.off))
+	if err != nil {
+		return 0, err
+	}
+	b.off += n
+	return unzigzag(u), nil
+}
+
+// ReadVarintAt reads Varint encoded integer at offset off. It returns
+// the value and its encoded length.
+func (b *Buffer) ReadVarintAt(off int64) (int64, int, error) {
+	i, err := b.rel(off)
+	if err != nil {
+		return 0, 0, err
+	}
+	u, n, err := uvarint(b.from(i))
+	return unzigzag(u), n, err
+}
+
+// WriteSLEB128 writes v encoded as signed LEB128 at the current offset
+// and advances the offset. It returns the number of bytes written.
+func (b *Buffer) WriteSLEB128(v int64) (int, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := putSLEB128(tmp[:], v)
+	return b.Write(tmp[:n])
+}
+
+// WriteSLEB128At writes v encoded as signed LEB128 at offset off. It
+// returns the number of bytes written.
+func (b *Buffer) WriteSLEB128At(off int64, v int64) (int, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := putSLEB128(tmp[:], v)
+	return b.WriteAt(tmp[:n], off)
+}
+
+// ReadSLEB128 reads signed LEB128 encoded integer at the current offset
+// and advances the offset.
+func (b *Buffer) ReadSLEB128() (int64, error) {
+	v, n, err := sleb128(b.from(b.off))
+	if err != nil {
+		return 0, err
+	}
+	b.off += n
+	return v, nil
+}
+
+// ReadSLEB128At reads signed LEB128 encoded integer at offset off. It
+// returns the value and its encoded length.
+func (b *Buffer) ReadSLEB128At(off int64) (int64, int, error) {
+	i, err := b.rel(off)
+	if err != nil {
+		return 0, 0, err
+	}
+	return sleb128(b.from(i))
+}
+
+// from returns the buffer bytes starting at index i.
+func (b *Buffer) from(i int) []byte {
+	if i >= len(b.buf) {
+		return nil
+	}
+	return b.buf[i:]
+}
+
+// uvarint decodes Uvarint from p. It returns the value and the number
+// of bytes read.
+func uvarint(p []byte) (uint64, int, error) {
+	var v uint64
+	var s uint
+	for i, c := range p {
+		if i == binary.MaxVarintLen64 {
+			return 0, 0, ErrOverflow
+		}
+		if c < 0x80 {
+			if i == binary.MaxVarintLen64-1 && c > 1 {
+				return 0, 0, ErrOverflow
+			}
+			return v | uint64(c)<<s, i + 1, nil
+		}
+		v |= uint64(c&0x7f) << s
+		s += 7
+	}
+	return 0, 0, truncated(p)
+}
+
+// unzigzag decodes zig-zag encoded signed integer.
+func unzigzag(u uint64) int64 {
+	v := int64(u >> 1)
+	if u&1 != 0 {
+		v = ^v
+	}
+	return v
+}
+
+// putSLEB128 encodes v as signed LEB128 into p and returns the number
+// of bytes written. The p must be at least binary.MaxVarintLen64 long.
+func putSLEB128(p []byte, v int64) int {
+	var i int
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			p[i] = c
+			return i + 1
+		}
+		p[i] = c | 0x80
+		i++
+	}
+}
+
+// sleb128 decodes signed LEB128 from p. It returns the value and the
+// number of bytes read.
+func sleb128(p []byte) (int64, int, error) {
+	var v int64
+	var s uint
+	for i, c := range p {
+		if i == binary.MaxVarintLen64 {
+			return 0, 0, ErrOverflow
+		}
+		v |= int64(c&0x7f) << s
+		s += 7
+		if c < 0x80 {
+			switch {
+			case i == binary.MaxVarintLen64-1:
+				// Only the sign bit is left, the remaining bits
+				// must be its extension.
+				if c != 0 && c != 0x7f {
+					return 0, 0, ErrOverflow
+				}
+			case c&0x40 != 0:
+				v |= -1 << s
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, truncated(p)
+}
+
+// truncated returns error for variable length integer p which is not
+// terminated.
+func truncated(p []byte) error {
+	switch {
+	case len(p) == 0:
+		return io.EOF
+	case len(p) >= binary.MaxVarintLen64:
+		return ErrOverflow
+	}
+	return io.ErrUnexpectedEOF
+}
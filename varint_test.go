@@ -0,0 +1,256 @@
+package flexbuf
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Buffer_Uvarint(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		v   uint64
+		exp []byte
+	}{
+		{"zero", 0, []byte{0x00}},
+		{"one byte", 127, []byte{0x7f}},
+		{"two bytes", 300, []byte{0xac, 0x02}},
+		{"max", math.MaxUint64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := &Buffer{}
+
+			// --- When ---
+			n, err := buf.WriteUvarint(tc.v)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, len(tc.exp), n)
+			assert.Exactly(t, tc.exp, buf.buf)
+
+			buf.SeekStart()
+			got, err := buf.ReadUvarint()
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.v, got)
+			assert.Exactly(t, len(tc.exp), buf.Offset())
+
+			// Compatible with io.ByteReader consumers.
+			buf.SeekStart()
+			got, err = binary.ReadUvarint(buf)
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.v, got)
+		})
+	}
+}
+
+func Test_Buffer_Varint(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		v   int64
+		exp []byte
+	}{
+		{"zero", 0, []byte{0x00}},
+		{"minus one", -1, []byte{0x01}},
+		{"one", 1, []byte{0x02}},
+		{"minus 65", -65, []byte{0x81, 0x01}},
+		{"min", math.MinInt64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"max", math.MaxInt64, []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := &Buffer{}
+
+			// --- When ---
+			n, err := buf.WriteVarint(tc.v)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, len(tc.exp), n)
+			assert.Exactly(t, tc.exp, buf.buf)
+
+			buf.SeekStart()
+			got, err := buf.ReadVarint()
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.v, got)
+			assert.Exactly(t, len(tc.exp), buf.Offset())
+		})
+	}
+}
+
+func Test_Buffer_SLEB128(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		v   int64
+		exp []byte
+	}{
+		{"zero", 0, []byte{0x00}},
+		{"two", 2, []byte{0x02}},
+		{"minus two", -2, []byte{0x7e}},
+		{"63", 63, []byte{0x3f}},
+		{"64", 64, []byte{0xc0, 0x00}},
+		{"minus 64", -64, []byte{0x40}},
+		{"minus 65", -65, []byte{0xbf, 0x7f}},
+		{"minus 123456", -123456, []byte{0xc0, 0xbb, 0x78}},
+		{"min", math.MinInt64, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7f}},
+		{"max", math.MaxInt64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := &Buffer{}
+
+			// --- When ---
+			n, err := buf.WriteSLEB128(tc.v)
+
+			// --- Then ---
+			assert.NoError(t, err)
+			assert.Exactly(t, len(tc.exp), n)
+			assert.Exactly(t, tc.exp, buf.buf)
+
+			buf.SeekStart()
+			got, err := buf.ReadSLEB128()
+			assert.NoError(t, err)
+			assert.Exactly(t, tc.v, got)
+			assert.Exactly(t, len(tc.exp), buf.Offset())
+		})
+	}
+}
+
+func Test_Buffer_Varint_At(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 0})
+
+	// --- When ---
+	n1, err1 := buf.WriteUvarintAt(1, 300)
+	n2, err2 := buf.WriteVarintAt(3, -65)
+	n3, err3 := buf.WriteSLEB128At(5, -65)
+
+	// --- Then ---
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	require.NoError(t, err3)
+	assert.Exactly(t, []int{2, 2, 2}, []int{n1, n2, n3})
+	assert.Exactly(t, []byte{0, 0xac, 0x02, 0x81, 0x01, 0xbf, 0x7f}, buf.buf)
+	assert.Exactly(t, 0, buf.Offset())
+
+	u, n, err := buf.ReadUvarintAt(1)
+	assert.NoError(t, err)
+	assert.Exactly(t, uint64(300), u)
+	assert.Exactly(t, 2, n)
+
+	v, n, err := buf.ReadVarintAt(3)
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(-65), v)
+	assert.Exactly(t, 2, n)
+
+	v, n, err = buf.ReadSLEB128At(5)
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(-65), v)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, 0, buf.Offset())
+
+	_, _, err = buf.ReadUvarintAt(-1)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	_, _, err = buf.ReadVarintAt(-1)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	_, _, err = buf.ReadSLEB128At(-1)
+	assert.Exactly(t, ErrOutOfBounds, err)
+}
+
+func Test_Buffer_Varint_Errors(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		data []byte
+		err  error
+	}{
+		{"empty", []byte{}, io.EOF},
+		{"truncated", []byte{0x80}, io.ErrUnexpectedEOF},
+		{"truncated long", []byte{0x80, 0x80, 0x80}, io.ErrUnexpectedEOF},
+		{"uvarint overflow last byte", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x02}, ErrOverflow},
+		{"too long", []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, ErrOverflow},
+		{"too long truncated", []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80}, ErrOverflow},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With(tc.data)
+
+			// --- When ---
+			_, err := buf.ReadUvarint()
+
+			// --- Then ---
+			assert.Exactly(t, tc.err, err)
+			assert.Exactly(t, 0, buf.Offset())
+			_, err = buf.ReadVarint()
+			assert.Exactly(t, tc.err, err)
+			_, _, err = buf.ReadUvarintAt(0)
+			assert.Exactly(t, tc.err, err)
+		})
+	}
+}
+
+func Test_Buffer_SLEB128_Errors(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		data []byte
+		err  error
+	}{
+		{"empty", []byte{}, io.EOF},
+		{"truncated", []byte{0xff}, io.ErrUnexpectedEOF},
+		{"overflow last byte", []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, ErrOverflow},
+		{"too long", []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, ErrOverflow},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With(tc.data)
+
+			// --- When ---
+			_, err := buf.ReadSLEB128()
+
+			// --- Then ---
+			assert.Exactly(t, tc.err, err)
+			assert.Exactly(t, 0, buf.Offset())
+			_, _, err = buf.ReadSLEB128At(0)
+			assert.Exactly(t, tc.err, err)
+		})
+	}
+}
+
+func Test_Buffer_Varint_NoAllocs(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	buf.Grow(1 << 10)
+
+	// --- When ---
+	allocs := testing.AllocsPerRun(100, func() {
+		buf.SeekStart()
+		_, _ = buf.WriteUvarint(math.MaxUint64)
+		_, _ = buf.WriteVarintAt(20, math.MinInt64)
+		_, _ = buf.WriteSLEB128(-1)
+		buf.SeekStart()
+		_, _ = buf.ReadUvarint()
+		_, _, _ = buf.ReadVarintAt(20)
+		_, _ = buf.ReadSLEB128()
+	})
+
+	// --- Then ---
+	assert.Exactly(t, float64(0), allocs)
+}
@@ -0,0 +1,78 @@
+package flexbuf
+
+// Discard discards the buffer data before the absolute offset off so its
+// memory can be reused. Offsets used by Offset, Seek, ReadAt, WriteAt
+// and Truncate stay absolute, accessing discarded data returns
+// ErrOutOfBounds. If the current offset is before off it's moved to off.
+// Discarding already discarded data is a no-op. The discarded bytes are
+// physically removed when there is at least as many of them as the bytes
+// kept so the cost of moving the data is amortized. It returns
+// ErrOutOfBounds if off is negative or beyond the end of the buffer.
+func (b *Buffer) Discard(off int64) error {
+	b.lock()
+	defer b.unlock()
+
+	i := off - b.base
+	if off < 0 || i > int64(len(b.buf)) {
+		return ErrOutOfBounds
+	}
+	if i <= int64(b.low) {
+		return nil
+	}
+
+	b.low = int(i)
+	b.invalidate(off, b.Size())
+	if b.off < b.low {
+		b.off = b.low
+	}
+	if b.low >= len(b.buf)-b.low {
+		b.compact()
+	}
+	return nil
+}
+
+// Compact moves the data kept after Discard to the beginning of the
+// underlying buffer making the space used by discarded bytes available
+// for new writes.
+func (b *Buffer) Compact() {
+	b.lock()
+	defer b.unlock()
+	b.compact()
+}
+
+// compact removes discarded bytes from the beginning of the buffer.
+func (b *Buffer) compact() {
+	if b.low == 0 {
+		return
+	}
+
+	b.own()
+	n := copy(b.buf, b.buf[b.low:])
+	zeroOutSlice(b.buf[n:])
+	b.buf = b.buf[:n]
+	b.base += int64(b.low)
+	b.off -= b.low
+	b.low = 0
+}
+
+// Base returns the absolute offset of the first not discarded byte.
+func (b *Buffer) Base() int64 {
+	return b.base + int64(b.low)
+}
+
+// Size returns the absolute offset of the end of the buffer, that is the
+// number of bytes written including discarded ones.
+func (b *Buffer) Size() int64 {
+	return b.base + int64(len(b.buf))
+}
+
+// rel returns the index in the underlying buffer of the byte at absolute
+// offset off. It returns ErrOutOfBounds if off is negative or points to
+// discarded data.
+func (b *Buffer) rel(off int64) (int, error) {
+	i := off - b.base
+	if off < 0 || i < int64(b.low) {
+		return 0, ErrOutOfBounds
+	}
+	return int(i), nil
+}
@@ -0,0 +1,219 @@
+package flexbuf
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Buffer_Discard(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	_, _ = buf.Seek(3, io.SeekStart)
+
+	// --- When ---
+	err := buf.Discard(2)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(2), buf.Base())
+	assert.Exactly(t, int64(8), buf.Size())
+	assert.Exactly(t, 6, buf.Len())
+	assert.Exactly(t, 3, buf.Offset())
+	assert.Exactly(t, 2, buf.low)
+
+	got := make([]byte, 2)
+	n, err := buf.Read(got)
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, []byte{3, 4}, got)
+}
+
+func Test_Buffer_Discard_MovesOffset(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+
+	// --- When ---
+	err := buf.Discard(3)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, 3, buf.Offset())
+	c, err := buf.ReadByte()
+	assert.NoError(t, err)
+	assert.Exactly(t, byte(3), c)
+}
+
+func Test_Buffer_Discard_Compacts(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	_, _ = buf.Seek(6, io.SeekStart)
+
+	// --- When ---
+	err := buf.Discard(5)
+
+	// --- Then ---
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(5), buf.base)
+	assert.Exactly(t, 0, buf.low)
+	assert.Exactly(t, []byte{5, 6, 7}, buf.buf)
+	assert.Exactly(t, []byte{5, 6, 7, 0, 0, 0, 0, 0}, buf.buf[:cap(buf.buf)])
+	assert.Exactly(t, 6, buf.Offset())
+	assert.Exactly(t, int64(8), buf.Size())
+}
+
+func Test_Buffer_Discard_Errors(t *testing.T) {
+	tt := []struct {
+		testN string
+
+		off int64
+		err error
+	}{
+		{"negative", -1, ErrOutOfBounds},
+		{"beyond end", 9, ErrOutOfBounds},
+		{"end", 8, nil},
+		{"already discarded", 1, nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.testN, func(t *testing.T) {
+			// --- Given ---
+			buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+			require.NoError(t, buf.Discard(2))
+
+			// --- When ---
+			err := buf.Discard(tc.off)
+
+			// --- Then ---
+			assert.Exactly(t, tc.err, err)
+		})
+	}
+}
+
+func Test_Buffer_Compact(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	_, _ = buf.Seek(0, io.SeekEnd)
+	require.NoError(t, buf.Discard(2))
+
+	// --- When ---
+	buf.Compact()
+
+	// --- Then ---
+	assert.Exactly(t, []byte{2, 3, 4, 5, 6, 7}, buf.buf)
+	assert.Exactly(t, int64(2), buf.Base())
+	assert.Exactly(t, 8, buf.Offset())
+
+	// New writes reuse the space.
+	_, _ = buf.Write([]byte{8, 9})
+	assert.Exactly(t, 8, buf.Cap())
+	assert.Exactly(t, int64(10), buf.Size())
+}
+
+func Test_Buffer_Discard_AbsoluteOffsets(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	require.NoError(t, buf.Discard(6))
+	require.Exactly(t, int64(6), buf.base)
+
+	// --- Then ---
+	got := make([]byte, 2)
+	n, err := buf.ReadAt(got, 6)
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, []byte{6, 7}, got)
+
+	_, err = buf.ReadAt(got, 5)
+	assert.Exactly(t, ErrOutOfBounds, err)
+
+	n, err = buf.WriteAt([]byte{9, 9}, 7)
+	assert.NoError(t, err)
+	assert.Exactly(t, 2, n)
+	assert.Exactly(t, int64(9), buf.Size())
+
+	_, err = buf.WriteAt([]byte{9}, 5)
+	assert.Exactly(t, ErrOutOfBounds, err)
+
+	off, err := buf.Seek(7, io.SeekStart)
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(7), off)
+	assert.Exactly(t, 7, buf.Offset())
+
+	_, err = buf.Seek(5, io.SeekStart)
+	assert.Exactly(t, ErrOutOfBounds, err)
+	_, err = buf.Seek(-8, io.SeekCurrent)
+	assert.Exactly(t, os.ErrInvalid, err)
+	off, err = buf.Seek(-1, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Exactly(t, int64(8), off)
+
+	assert.Exactly(t, int64(8), buf.SeekStart())
+	assert.Exactly(t, 6, buf.Offset())
+	assert.Exactly(t, int64(6), buf.SeekEnd())
+	assert.Exactly(t, 9, buf.Offset())
+
+	assert.Exactly(t, ErrOutOfBounds, buf.Truncate(5))
+	assert.NoError(t, buf.Truncate(7))
+	assert.Exactly(t, int64(7), buf.Size())
+}
+
+func Test_Buffer_Discard_Release(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	require.NoError(t, buf.Discard(2))
+
+	// --- When ---
+	got := buf.Release()
+
+	// --- Then ---
+	assert.Exactly(t, []byte{2, 3, 4, 5, 6, 7}, got)
+	assert.Exactly(t, int64(0), buf.Base())
+}
+
+func Test_Buffer_Discard_Follower(t *testing.T) {
+	// --- Given ---
+	buf := With([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	f1 := buf.Follow(2)
+	f2 := buf.Follow(7)
+
+	// --- When ---
+	require.NoError(t, buf.Discard(6))
+
+	// --- Then ---
+	_, err := f1.Read(make([]byte, 1))
+	assert.Exactly(t, ErrOutOfBounds, err)
+
+	got := make([]byte, 4)
+	n, err := f2.Read(got)
+	assert.NoError(t, err)
+	assert.Exactly(t, []byte{7}, got[:n])
+}
+
+func Test_Buffer_Discard_Stream(t *testing.T) {
+	// --- Given ---
+	buf := &Buffer{}
+	data := testData(10000)
+
+	// --- When ---
+	var got []byte
+	tmp := make([]byte, 30)
+	for i := 0; i < len(data); i += 100 {
+		_, _ = buf.WriteAt(data[i:i+100], buf.Size())
+		for {
+			n, _ := buf.Read(tmp)
+			if n == 0 {
+				break
+			}
+			got = append(got, tmp[:n]...)
+		}
+		require.NoError(t, buf.Discard(int64(buf.Offset())))
+	}
+
+	// --- Then ---
+	assert.Exactly(t, data, got)
+	assert.Exactly(t, int64(10000), buf.Size())
+	assert.True(t, buf.Cap() <= 256)
+}